@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	cloudfrontsvc "github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	cf "github.com/jimmysawczuk/aws-tools/internal/cloudfront"
+	"github.com/jimmysawczuk/aws-tools/internal/safefile"
+)
+
+// Exit codes follow diff(1) when comparing: 0 if the configs agree, 1 if
+// they differ and 2 if they couldn't be compared.
+const (
+	exitSame      = 0
+	exitDifferent = 1
+	exitError     = 2
+)
+
+func main() {
+	var out string
+	var mode string
+	var force bool
+	var format string
+
+	flag.StringVar(&out, "out", "", "with one argument, write the config snapshot to this file instead of stdout")
+	flag.StringVar(&mode, "mode", "0644", "file mode (octal) used when writing to -out")
+	flag.BoolVar(&force, "force", false, "overwrite -out if it already exists")
+	flag.StringVar(&format, "format", "text", "with two arguments, the output format of the differences: text or json")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <distribution>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] <distribution or snapshot> <distribution or snapshot>\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "with one argument, the distribution's normalized config is exported as JSON.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "with two, their configs are compared. An argument naming an existing file is read as a snapshot;\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "anything else is a distribution ID, alias, tag:key=value or comment:text.\n\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if format != "text" && format != "json" {
+		log.Printf("unknown format: %s", format)
+		os.Exit(exitError)
+	}
+
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(exitError)
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Printf("unable to load AWS config: %v", err)
+		os.Exit(exitError)
+	}
+
+	cloudfront := cloudfrontsvc.NewFromConfig(cfg)
+
+	if flag.NArg() == 1 {
+		if err := export(ctx, cloudfront, flag.Arg(0), out, mode, force); err != nil {
+			log.Printf("couldn't export config: %s", err)
+			os.Exit(exitError)
+		}
+		return
+	}
+
+	a, b := flag.Arg(0), flag.Arg(1)
+
+	left, err := load(ctx, cloudfront, a)
+	if err != nil {
+		log.Printf("couldn't read %s: %s", a, err)
+		os.Exit(exitError)
+	}
+
+	right, err := load(ctx, cloudfront, b)
+	if err != nil {
+		log.Printf("couldn't read %s: %s", b, err)
+		os.Exit(exitError)
+	}
+
+	changes := cf.DiffConfigs(left, right)
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if changes == nil {
+			changes = []cf.ConfigChange{}
+		}
+		if err := enc.Encode(changes); err != nil {
+			log.Printf("json: encode: %s", err)
+			os.Exit(exitError)
+		}
+	} else {
+		printChanges(a, b, changes)
+	}
+
+	if len(changes) > 0 {
+		log.Printf("%d settings differ", len(changes))
+		os.Exit(exitDifferent)
+	}
+
+	log.Println("configs identical")
+	os.Exit(exitSame)
+}
+
+func export(ctx context.Context, cloudfront *cloudfrontsvc.Client, selector string, out string, mode string, force bool) error {
+	dist, err := cf.ResolveDistribution(ctx, cloudfront, selector)
+	if err != nil {
+		return fmt.Errorf("resolve distribution: %w", err)
+	}
+
+	log.Printf("%s: %s", dist.ID, dist.Comment)
+
+	c, err := cf.ExportConfig(ctx, cloudfront, dist.ID)
+	if err != nil {
+		return err
+	}
+
+	by, err := c.Encode()
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err := os.Stdout.Write(by)
+		return err
+	}
+
+	fm, err := safefile.ParseMode(mode)
+	if err != nil {
+		return fmt.Errorf("parse mode: %w", err)
+	}
+
+	n, err := safefile.Write(out, bytes.NewReader(by), safefile.Options{Mode: fm, Overwrite: force})
+	if err != nil {
+		return safefile.ForceHint(err)
+	}
+
+	log.Printf("wrote %d bytes to %s", n, out)
+	return nil
+}
+
+// load reads a snapshot if arg names a file, and otherwise exports the
+// config of the distribution it selects.
+func load(ctx context.Context, cloudfront *cloudfrontsvc.Client, arg string) (cf.Config, error) {
+	if fi, err := os.Stat(arg); err == nil && !fi.IsDir() {
+		return cf.ReadConfig(arg)
+	}
+
+	dist, err := cf.ResolveDistribution(ctx, cloudfront, arg)
+	if err != nil {
+		return nil, fmt.Errorf("resolve distribution: %w", err)
+	}
+
+	return cf.ExportConfig(ctx, cloudfront, dist.ID)
+}
+
+func printChanges(a, b string, changes []cf.ConfigChange) {
+	fmt.Printf("--- %s\n+++ %s\n", a, b)
+	cf.WriteChanges(os.Stdout, changes)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	cloudfrontsvc "github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	cf "github.com/jimmysawczuk/aws-tools/internal/cloudfront"
+	"github.com/jimmysawczuk/aws-tools/internal/s3sync"
+)
+
+func main() {
+	var distSelector string
+	var bucket string
+	var prefix string
+	var region string
+	var endpoint string
+	var cacheRules cacheRules
+	var defaultCacheControl string
+	var deletes bool
+	var force bool
+	var concurrency int
+	var dryRun bool
+	var invalidate bool
+	var maxPaths int
+	var freePaths int
+	var callerRef string
+	var wait bool
+	var timeout time.Duration
+
+	flag.StringVar(&distSelector, "distribution", "", "distribution to deploy to: an ID, alias, tag:key=value or comment:text")
+	flag.StringVar(&bucket, "bucket", "", "bucket to upload to (default the distribution's S3 origin)")
+	flag.StringVar(&prefix, "prefix", "", "key prefix to upload under (default the origin path)")
+	flag.StringVar(&region, "region", "", "region of the bucket (default taken from the origin, then the AWS config)")
+	flag.StringVar(&endpoint, "endpoint", "", "S3 endpoint URL, e.g. http://localhost:9000 for a local S3 stand-in (uses path-style addressing)")
+	flag.Var(&cacheRules, "cache-control", "Cache-Control for files matching a glob, e.g. 'assets/**=public, max-age=31536000, immutable' (repeatable; first match wins)")
+	flag.StringVar(&defaultCacheControl, "default-cache-control", "", "Cache-Control for files matching no -cache-control rule")
+	flag.BoolVar(&deletes, "delete", true, "delete objects that aren't in the build directory")
+	flag.BoolVar(&force, "force", false, "upload every file, even unchanged ones (e.g. to apply new cache rules)")
+	flag.IntVar(&concurrency, "concurrency", 8, "how many files to upload at once")
+	flag.BoolVar(&dryRun, "dry-run", true, "set to false to actually upload and invalidate")
+	flag.BoolVar(&invalidate, "invalidate", true, "invalidate the changed files once they're uploaded")
+	flag.IntVar(&maxPaths, "max-paths", 100, "the most invalidation paths before falling back to wildcards (0 for no limit)")
+	flag.IntVar(&freePaths, "free-paths", 1000, "invalidation paths left in this month's free tier; beyond it, wildcards are preferred where they cost less")
+	flag.StringVar(&callerRef, "caller-reference", "", "caller reference (e.g. a commit SHA) that makes repeated runs reuse the same invalidation")
+	flag.BoolVar(&wait, "wait", true, "wait for the invalidation to complete")
+	flag.DurationVar(&timeout, "timeout", 20*time.Minute, "how long to wait for invalidations to complete (0 for no limit)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] -distribution <selector> <build dir>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] -bucket <bucket> -invalidate=false <build dir>\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := flag.Arg(0)
+
+	if distSelector == "" && (bucket == "" || invalidate) {
+		log.Fatal("-distribution is required, unless -bucket is set and -invalidate=false")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	cloudfront := cloudfrontsvc.NewFromConfig(cfg)
+
+	var dist *cf.Distribution
+	if distSelector != "" {
+		dist, err = cf.ResolveDistribution(ctx, cloudfront, distSelector)
+		if err != nil {
+			log.Fatalf("couldn't resolve distribution: %s", err)
+		}
+
+		log.Printf("%s: %s", dist.ID, dist.Comment)
+	}
+
+	if bucket == "" {
+		origin, err := cf.GetS3Origin(ctx, cloudfront, dist.ID)
+		if err != nil {
+			log.Fatalf("couldn't find the distribution's bucket: %s", err)
+		}
+
+		bucket = origin.Bucket
+		if prefix == "" {
+			prefix = origin.Path
+		}
+		if region == "" {
+			region = origin.Region
+		}
+	}
+
+	prefix = s3sync.NormalizePrefix(prefix)
+
+	api := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if region != "" {
+			o.Region = region
+		}
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+
+	log.Printf("syncing %s to s3://%s/%s", dir, bucket, prefix)
+
+	local, err := s3sync.ScanDir(dir)
+	if err != nil {
+		log.Fatalf("couldn't read build directory: %s", err)
+	}
+
+	remote, err := s3sync.ListObjects(ctx, api, bucket, prefix)
+	if err != nil {
+		log.Fatalf("couldn't list bucket: %s", err)
+	}
+
+	actions := s3sync.Plan(local, remote, deletes, force)
+
+	syncer := &s3sync.Syncer{
+		API:                 api,
+		Bucket:              bucket,
+		Prefix:              prefix,
+		Rules:               cacheRules,
+		DefaultCacheControl: defaultCacheControl,
+		Concurrency:         concurrency,
+		Logf:                log.Printf,
+	}
+
+	uploads, removals := 0, 0
+	var changed []string
+	for _, a := range actions {
+		changed = append(changed, a.Name)
+
+		if a.Op == '-' {
+			removals++
+			log.Printf("- %s", a.Name)
+			continue
+		}
+
+		uploads++
+		contentType, cacheControl, err := syncer.Headers(local[a.Name])
+		if err != nil {
+			log.Fatalf("couldn't read %s: %s", a.Name, err)
+		}
+		log.Printf("%c %s (%s, %s)", a.Op, a.Name, contentType, cacheControl)
+	}
+
+	log.Printf("%d files, %d to upload, %d to delete", len(local), uploads, removals)
+
+	var plan cf.Plan
+	if invalidate && len(changed) > 0 {
+		// The planner can only judge how much a wildcard sweeps with every
+		// served path, which is every file before and after the deploy.
+		var universe []string
+		for name := range local {
+			universe = append(universe, name)
+		}
+		for name := range remote {
+			if _, ok := local[name]; !ok {
+				universe = append(universe, name)
+			}
+		}
+
+		plan = cf.PlanPaths(cf.URLPaths(changed), cf.PlanOptions{
+			MaxPaths:      maxPaths,
+			Universe:      cf.URLPaths(universe),
+			MaxCollateral: 0.5,
+			FreePaths:     freePaths,
+		})
+
+		log.Printf("invalidation: %d paths (%d wildcards)", len(plan.Paths), plan.Wildcards)
+		for _, r := range plan.Reasons {
+			log.Printf("  %s", r)
+		}
+	}
+
+	if len(actions) == 0 {
+		log.Println("already in sync")
+		return
+	}
+
+	if dryRun {
+		log.Println("dry run, pass -dry-run=false to deploy")
+		for _, p := range plan.Paths {
+			fmt.Println(p)
+		}
+		return
+	}
+
+	if err := syncer.Apply(ctx, local, actions); err != nil {
+		log.Fatalf("couldn't sync: %s", err)
+	}
+
+	log.Printf("uploaded %d files, deleted %d objects", uploads, removals)
+
+	if !invalidate {
+		return
+	}
+
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	batches := cf.Batch(plan.Paths)
+	for i, batch := range batches {
+		ref := cf.CallerReference(callerRef, i, len(batches))
+
+		inv, existed, err := cf.CreateInvalidation(ctx, cloudfront, dist.ID, ref, batch)
+		if err != nil {
+			log.Fatalf("couldn't invalidate distribution: %s", err)
+		}
+
+		switch {
+		case !existed:
+			log.Printf("invalidation created: %s (batch %d of %d, %d paths)", inv.ID, i+1, len(batches), len(batch))
+		case inv.SamePaths(batch):
+			log.Printf("invalidation already exists for caller reference %s: %s (%s)", ref, inv.ID, inv.Status)
+		default:
+			log.Printf("warning: invalidation %s already exists for caller reference %s with different paths (%d instead of %d); not invalidating again", inv.ID, ref, len(inv.Paths), len(batch))
+		}
+		fmt.Println(inv.ID)
+
+		if !wait && i == len(batches)-1 {
+			break
+		}
+
+		if err := cf.WaitForInvalidation(waitCtx, cloudfront, dist.ID, inv.ID, log.Printf); err != nil {
+			log.Printf("couldn't wait for invalidation %s: %s", inv.ID, err)
+			os.Exit(cf.ExitCode(err))
+		}
+	}
+}
+
+type cacheRules []s3sync.CacheRule
+
+func (c *cacheRules) String() string {
+	var tbr []string
+	for _, r := range *c {
+		tbr = append(tbr, r.Pattern+"="+r.Value)
+	}
+
+	return strings.Join(tbr, ",")
+}
+
+func (c *cacheRules) Set(v string) error {
+	r, err := s3sync.ParseCacheRule(v)
+	if err != nil {
+		return err
+	}
+
+	*c = append(*c, r)
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const diffContext = 3
+
+type edit struct {
+	op   byte
+	line string
+}
+
+// unifiedDiff writes a unified diff of two texts to w, and reports whether
+// they differ. Functions are small, so a plain LCS table is fast enough.
+func unifiedDiff(w io.Writer, aName, bName string, a, b string) bool {
+	al := splitLines(a)
+	bl := splitLines(b)
+
+	edits := lineEdits(al, bl)
+
+	changed := false
+	for _, e := range edits {
+		if e.op != ' ' {
+			changed = true
+			break
+		}
+	}
+
+	if !changed {
+		return false
+	}
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", aName, bName)
+
+	// Walk the edits, emitting each run of changes with up to diffContext
+	// unchanged lines around it; runs closer together than that share a
+	// hunk.
+	aLine, bLine := 1, 1
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			aLine++
+			bLine++
+			continue
+		}
+
+		start := max(i-diffContext, 0)
+		end := i
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+
+			next := end
+			for next < len(edits) && edits[next].op == ' ' {
+				next++
+			}
+
+			if next == len(edits) || next-end > 2*diffContext {
+				end = min(end+diffContext, len(edits))
+				break
+			}
+
+			end = next
+		}
+
+		aStart, bStart := aLine-(i-start), bLine-(i-start)
+		aCount, bCount := 0, 0
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				aCount++
+			}
+			if e.op != '-' {
+				bCount++
+			}
+		}
+
+		// An empty side is numbered by the line before it.
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, e := range edits[start:end] {
+			fmt.Fprintf(w, "%c%s\n", e.op, e.line)
+		}
+
+		for _, e := range edits[i:end] {
+			if e.op != '+' {
+				aLine++
+			}
+			if e.op != '-' {
+				bLine++
+			}
+		}
+
+		i = end
+	}
+
+	return true
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func lineEdits(a, b []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var tbr []edit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			tbr = append(tbr, edit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			tbr = append(tbr, edit{'-', a[i]})
+			i++
+		default:
+			tbr = append(tbr, edit{'+', b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		tbr = append(tbr, edit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		tbr = append(tbr, edit{'+', b[j]})
+	}
+
+	return tbr
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLineEdits(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{a: "", b: "", want: ""},
+		{a: "a\nb\n", b: "a\nb\n", want: " a  b"},
+		{a: "", b: "a\n", want: "+a"},
+		{a: "a\n", b: "", want: "-a"},
+		{a: "a\nb\nc\n", b: "a\nx\nc\n", want: " a -b +x  c"},
+		{a: "a\nb\nc\nd\n", b: "b\nd\ne\n", want: "-a  b -c  d +e"},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, e := range lineEdits(splitLines(tt.a), splitLines(tt.b)) {
+			got = append(got, string(e.op)+e.line)
+		}
+
+		if s := strings.Join(got, " "); s != tt.want {
+			t.Errorf("%q -> %q: got %q, want %q", tt.a, tt.b, s, tt.want)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n"
+	b := strings.Replace(strings.Replace(a, "2\n", "two\n", 1), "18\n", "", 1)
+
+	var buf bytes.Buffer
+	if !unifiedDiff(&buf, "live", "new", a, b) {
+		t.Fatal("got no difference")
+	}
+
+	want := `--- live
++++ new
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -15,6 +15,5 @@
+ 15
+ 16
+ 17
+-18
+ 19
+ 20
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if unifiedDiff(&buf, "live", "new", a, a) || buf.Len() != 0 {
+		t.Errorf("same text: got %q", buf.String())
+	}
+
+	buf.Reset()
+	unifiedDiff(&buf, "live", "new", "", "a\n")
+	if got, want := buf.String(), "--- live\n+++ new\n@@ -0,0 +1,1 @@\n+a\n"; got != want {
+		t.Errorf("new file: got %q, want %q", got, want)
+	}
+}
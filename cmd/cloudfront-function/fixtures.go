@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// fixture is a test event and what the function should do with it. Expect
+// only needs to contain the parts of the output that matter, e.g.
+// {"response": {"statusCode": 301}}; anything it doesn't mention is ignored.
+type fixture struct {
+	Name        string          `json:"-"`
+	Event       json.RawMessage `json:"event"`
+	Expect      any             `json:"expect"`
+	ExpectError string          `json:"expectError"`
+}
+
+// readFixtures reads every *.json file in dir, in name order.
+func readFixtures(dir string) ([]fixture, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("glob fixtures: %w", err)
+	}
+
+	sort.Strings(names)
+
+	var tbr []fixture
+	for _, name := range names {
+		by, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("os: read file: %w", err)
+		}
+
+		var f fixture
+		if err := json.Unmarshal(by, &f); err != nil {
+			return nil, fmt.Errorf("json: unmarshal fixture (%s): %w", name, err)
+		}
+
+		if len(f.Event) == 0 {
+			return nil, fmt.Errorf("fixture %s has no event", name)
+		}
+
+		f.Name = strings.TrimSuffix(filepath.Base(name), ".json")
+		tbr = append(tbr, f)
+	}
+
+	return tbr, nil
+}
+
+// check compares a test result with the fixture's expectations, returning a
+// description of each mismatch.
+func (f fixture) check(output string, errMessage string) []string {
+	if f.ExpectError != "" {
+		if !strings.Contains(errMessage, f.ExpectError) {
+			return []string{fmt.Sprintf("expected an error containing %q, got %q", f.ExpectError, errMessage)}
+		}
+		return nil
+	}
+
+	if errMessage != "" {
+		return []string{fmt.Sprintf("function failed: %s", errMessage)}
+	}
+
+	if f.Expect == nil {
+		return nil
+	}
+
+	var got any
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		return []string{fmt.Sprintf("output isn't JSON: %s", err)}
+	}
+
+	return match("", f.Expect, got)
+}
+
+// match reports where got differs from want, treating objects in want as a
+// subset of those in got.
+func match(path string, want, got any) []string {
+	if w, ok := want.(map[string]any); ok {
+		g, ok := got.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %s", label(path), compact(got))}
+		}
+
+		keys := make([]string, 0, len(w))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var tbr []string
+		for _, k := range keys {
+			gv, ok := g[k]
+			if !ok {
+				tbr = append(tbr, fmt.Sprintf("%s: missing", label(path+"."+k)))
+				continue
+			}
+			tbr = append(tbr, match(path+"."+k, w[k], gv)...)
+		}
+
+		return tbr
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", label(path), compact(want), compact(got))}
+	}
+
+	return nil
+}
+
+func label(path string) string {
+	if path == "" {
+		return "output"
+	}
+
+	return strings.TrimPrefix(path, ".")
+}
+
+func compact(v any) string {
+	by, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(by)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	cloudfrontsvc "github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	cloudfronttypes "github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	cf "github.com/jimmysawczuk/aws-tools/internal/cloudfront"
+)
+
+func main() {
+	var fixturesDir string
+	var runtime string
+	var comment string
+	var publish bool
+	var dryRun bool
+	var verbose bool
+
+	flag.StringVar(&fixturesDir, "fixtures", "", "directory of JSON test fixtures, each with an \"event\" and an \"expect\" (or \"expectError\")")
+	flag.StringVar(&runtime, "runtime", "", fmt.Sprintf("function runtime (if unset, an existing function keeps its runtime and a new one gets %s)", cf.DefaultFunctionRuntime))
+	flag.StringVar(&comment, "comment", "", "function comment (if unset, an existing function keeps its comment)")
+	flag.BoolVar(&publish, "publish", false, "publish to LIVE if every fixture passes")
+	flag.BoolVar(&dryRun, "dry-run", false, "show the diff against LIVE without changing anything")
+	flag.BoolVar(&verbose, "v", false, "print the function's logs for every fixture, not just failing ones")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <function name> <file.js>\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	name, file := flag.Arg(0), flag.Arg(1)
+
+	// Only flags given on the command line change the function's config.
+	var fnCfg cf.FunctionConfig
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "runtime":
+			r := cloudfronttypes.FunctionRuntime(runtime)
+			fnCfg.Runtime = &r
+		case "comment":
+			fnCfg.Comment = &comment
+		}
+	})
+
+	code, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("couldn't read function code: %s", err)
+	}
+
+	var fixtures []fixture
+	if fixturesDir != "" {
+		fixtures, err = readFixtures(fixturesDir)
+		if err != nil {
+			log.Fatalf("couldn't read fixtures: %s", err)
+		}
+
+		if len(fixtures) == 0 {
+			log.Fatalf("no fixtures found in %s", fixturesDir)
+		}
+	}
+
+	if publish && len(fixtures) == 0 {
+		log.Fatal("-publish requires -fixtures, so nothing untested goes live")
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	cloudfront := cloudfrontsvc.NewFromConfig(cfg)
+
+	live, err := cf.GetFunction(ctx, cloudfront, name, cloudfronttypes.FunctionStageLive)
+	if err != nil {
+		log.Fatalf("couldn't get live function: %s", err)
+	}
+
+	liveCode := ""
+	if live != nil {
+		liveCode = string(live.Code)
+	}
+
+	if !unifiedDiff(os.Stdout, name+" (LIVE)", file, liveCode, string(code)) {
+		log.Println("code matches LIVE")
+	}
+
+	if dryRun {
+		log.Println("dry run, not deploying")
+		return
+	}
+
+	dev, err := cf.GetFunction(ctx, cloudfront, name, cloudfronttypes.FunctionStageDevelopment)
+	if err != nil {
+		log.Fatalf("couldn't get function: %s", err)
+	}
+
+	etag := ""
+	switch {
+	case dev == nil:
+		etag, err = cf.PutFunction(ctx, cloudfront, nil, name, code, fnCfg)
+		if err != nil {
+			log.Fatalf("couldn't create function: %s", err)
+		}
+		log.Printf("created %s", name)
+
+	case dev.Differs(code, fnCfg):
+		etag, err = cf.PutFunction(ctx, cloudfront, dev, name, code, fnCfg)
+		if err != nil {
+			log.Fatalf("couldn't update function: %s", err)
+		}
+		log.Printf("updated %s (DEVELOPMENT)", name)
+
+	default:
+		etag = dev.ETag
+		log.Printf("%s (DEVELOPMENT) is up to date", name)
+	}
+
+	failed := 0
+	for _, f := range fixtures {
+		res, err := cf.TestFunction(ctx, cloudfront, name, etag, f.Event)
+		if err != nil {
+			log.Fatalf("couldn't test fixture %s: %s", f.Name, err)
+		}
+
+		problems := f.check(res.Output, res.Error)
+		if len(problems) == 0 {
+			log.Printf("PASS %s (compute utilization %s)", f.Name, res.Utilization)
+		} else {
+			failed++
+			log.Printf("FAIL %s", f.Name)
+			for _, p := range problems {
+				log.Printf("    %s", p)
+			}
+		}
+
+		if verbose || len(problems) > 0 {
+			for _, l := range res.Logs {
+				log.Printf("    log: %s", strings.TrimSpace(l))
+			}
+		}
+	}
+
+	if failed > 0 {
+		log.Fatalf("%d of %d fixtures failed, not publishing", failed, len(fixtures))
+	}
+
+	if len(fixtures) > 0 {
+		log.Printf("all %d fixtures passed", len(fixtures))
+	}
+
+	if !publish {
+		return
+	}
+
+	// LIVE gets DEVELOPMENT's config, whether or not it was just changed.
+	devRuntime, devComment := fnCfg.Resolve(dev)
+	if live != nil && !live.Differs(code, cf.FunctionConfig{Runtime: &devRuntime, Comment: &devComment}) {
+		log.Println("LIVE is already up to date")
+		return
+	}
+
+	if err := cf.PublishFunction(ctx, cloudfront, name, etag); err != nil {
+		log.Fatalf("couldn't publish function: %s", err)
+	}
+
+	log.Printf("published %s to LIVE", name)
+}
@@ -1,25 +1,74 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	cloudfrontsvc "github.com/aws/aws-sdk-go-v2/service/cloudfront"
-	cloudfronttypes "github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	cf "github.com/jimmysawczuk/aws-tools/internal/cloudfront"
+	"github.com/jimmysawczuk/aws-tools/internal/safefile"
 )
 
 var cloudfront *cloudfrontsvc.Client
 
 func main() {
+	var pathsFile string
+	var gitRange string
+	var gitRepo string
+	var gitSubdir string
+	var buildDir string
+	var manifestFile string
+	var wildcardThreshold int
+	var optimize bool
+	var maxPaths int
+	var maxCollateral float64
+	var freePaths int
+	var dryRun bool
+	var wait bool
+	var noWait bool
+	var timeout time.Duration
+	var callerRef string
+	var distSelectors selectors
+
+	flag.Var(&distSelectors, "distribution", "distribution to invalidate: an ID, alias, tag:key=value or comment:text (repeatable; if unset, the first argument is used)")
+	flag.StringVar(&pathsFile, "paths-file", "", "file with one path to invalidate per line (- for stdin)")
+	flag.StringVar(&gitRange, "git-range", "", "invalidate the files changed in this git revision range, e.g. v1.2.0..HEAD")
+	flag.StringVar(&gitRepo, "git-repo", ".", "git repository used with -git-range")
+	flag.StringVar(&gitSubdir, "git-subdir", "", "directory within the git repository that's served at the site root")
+	flag.StringVar(&buildDir, "build-dir", "", "invalidate the files in this build directory that changed since -manifest")
+	flag.StringVar(&manifestFile, "manifest", ".cloudfront-manifest.json", "file-hash manifest from the previous deploy, updated after a successful invalidation")
+	flag.IntVar(&wildcardThreshold, "wildcard-threshold", 50, "replace a directory's changed paths with dir/* when there are more than this many (0 to disable, ignored with -optimize)")
+	flag.BoolVar(&optimize, "optimize", false, "plan the cheapest set of explicit paths and wildcards that covers the changed paths, sweeping as few unchanged paths as possible at that cost")
+	flag.IntVar(&maxPaths, "max-paths", 100, "with -optimize, the most paths to invalidate before falling back to wildcards (0 for no limit)")
+	flag.Float64Var(&maxCollateral, "max-collateral", 0.5, "with -optimize and -build-dir, the largest share of unchanged files a wildcard may sweep")
+	flag.IntVar(&freePaths, "free-paths", 1000, "invalidation paths left in this month's free tier, for cost estimates and -optimize")
+	flag.BoolVar(&dryRun, "dry-run", false, "print the paths that would be invalidated without invalidating them")
+	flag.BoolVar(&wait, "wait", true, "wait for the invalidation to complete")
+	flag.BoolVar(&noWait, "no-wait", false, "don't wait for the invalidation to complete (same as -wait=false); when paths are split into several batches, every batch but the last is still waited on")
+	flag.StringVar(&callerRef, "caller-reference", "", "caller reference (e.g. a commit SHA or deploy ID) that makes repeated runs reuse the same invalidation; suffixed with the batch number when there are several batches")
+	flag.DurationVar(&timeout, "timeout", 20*time.Minute, "how long to wait for invalidations to complete (0 for no limit)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <distribution id or alias> [path...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] -distribution <selector> [-distribution <selector>...] [path...]\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
 	flag.Parse()
 
-	ctx := context.Background()
+	if noWait {
+		wait = false
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
 	cfg, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
@@ -28,134 +77,219 @@ func main() {
 
 	cloudfront = cloudfrontsvc.NewFromConfig(cfg)
 
-	dist, path, err := parseArgs(ctx, flag.Args())
+	dists, paths, err := parseArgs(ctx, distSelectors, flag.Args())
 	if err != nil {
 		log.Fatalf("couldn't parse args: %s", err)
 	}
 
-	log.Printf("%s: %s", dist.ID, dist.Comment)
-	for _, v := range dist.Aliases {
-		log.Printf(" - %s", v)
+	if pathsFile != "" {
+		fromFile, err := readPathsFile(pathsFile)
+		if err != nil {
+			log.Fatalf("couldn't read paths: %s", err)
+		}
+
+		paths = append(paths, fromFile...)
 	}
 
-	invalidation, err := invalidateDistribution(ctx, dist.ID, path)
-	if err != nil {
-		log.Fatalf("couldn't invalidate distribution: %s", err)
-		return
+	computed := gitRange != "" || buildDir != ""
+
+	if optimize {
+		wildcardThreshold = 0
 	}
 
-	log.Printf("invalidation created: %s", invalidation)
+	if gitRange != "" {
+		files, err := cf.GitChanges(gitRepo, gitRange, gitSubdir)
+		if err != nil {
+			log.Fatalf("couldn't get changed files: %s", err)
+		}
 
-	for {
-		resp, err := cloudfront.GetInvalidation(ctx, &cloudfrontsvc.GetInvalidationInput{
-			DistributionId: aws.String(dist.ID),
-			Id:             aws.String(invalidation),
-		})
+		log.Printf("%d files changed in %s", len(files), gitRange)
+		paths = append(paths, cf.CollapseDirs(cf.URLPaths(files), wildcardThreshold)...)
+	}
+
+	var manifest cf.Manifest
+	if buildDir != "" {
+		prev, err := cf.ReadManifest(manifestFile)
 		if err != nil {
-			log.Printf("couldn't get status: %s", err)
-			time.Sleep(5 * time.Second)
+			log.Fatalf("couldn't read manifest: %s", err)
 		}
 
-		if aws.ToString(resp.Invalidation.Status) == "Completed" {
-			log.Println("invalidation complete")
-			break
+		manifest, err = cf.BuildManifest(buildDir)
+		if err != nil {
+			log.Fatalf("couldn't hash build directory: %s", err)
 		}
 
-		log.Println("waiting on invalidation to complete")
-		time.Sleep(5 * time.Second)
+		files := manifest.Changed(prev)
+		log.Printf("%d files changed in %s since last deploy", len(files), buildDir)
+		paths = append(paths, cf.CollapseDirs(cf.URLPaths(files), wildcardThreshold)...)
 	}
-}
 
-type Distribution struct {
-	ID      string
-	ARN     string
-	Comment string
-	Aliases []string
-}
+	paths = cf.NormalizePaths(paths)
+	if len(paths) == 0 {
+		if computed {
+			log.Println("nothing changed, no invalidation needed")
+			return
+		}
 
-func getDistribution(ctx context.Context, id string) (*Distribution, error) {
-	resp, err := cloudfront.GetDistribution(ctx, &cloudfrontsvc.GetDistributionInput{
-		Id: aws.String(id),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("aws: cloudfront: get distribution: %w", err)
+		paths = []string{"/*"}
 	}
 
-	dist := Distribution{
-		ID:      aws.ToString(resp.Distribution.Id),
-		ARN:     aws.ToString(resp.Distribution.ARN),
-		Comment: aws.ToString(resp.Distribution.DistributionConfig.Comment),
-	}
+	if optimize {
+		opts := cf.PlanOptions{
+			MaxPaths:  maxPaths,
+			FreePaths: freePaths,
+		}
 
-	dist.Aliases = append(dist.Aliases, resp.Distribution.DistributionConfig.Aliases.Items...)
+		if manifest != nil {
+			opts.Universe = cf.URLPaths(manifest.Files())
+			opts.MaxCollateral = maxCollateral
+		}
 
-	return &dist, nil
-}
+		plan := cf.PlanPaths(paths, opts)
 
-func invalidateDistribution(ctx context.Context, id string, path string) (string, error) {
-	resp, err := cloudfront.CreateInvalidation(ctx, &cloudfrontsvc.CreateInvalidationInput{
-		DistributionId: aws.String(id),
-		InvalidationBatch: &cloudfronttypes.InvalidationBatch{
-			CallerReference: aws.String(time.Now().Format("20060102150405")),
-			Paths: &cloudfronttypes.Paths{
-				Items:    []string{path},
-				Quantity: aws.Int32(1),
-			},
-		},
-	})
-	if err != nil {
-		return "", fmt.Errorf("aws: cloudfront: create invalidation: %w", err)
+		log.Printf("plan: %d changed paths -> %d paths (%d wildcards), est. cost $%.3f", plan.Requested, len(plan.Paths), plan.Wildcards, plan.Cost(freePaths))
+		for _, r := range plan.Reasons {
+			log.Printf(" - %s", r)
+		}
+
+		if plan.OverLimit {
+			log.Printf("couldn't get under %d paths within the collateral limit", maxPaths)
+		}
+
+		paths = plan.Paths
 	}
 
-	return aws.ToString(resp.Invalidation.Id), nil
-}
+	if dryRun {
+		for _, p := range paths {
+			fmt.Println(p)
+		}
 
-func parseArgs(ctx context.Context, args []string) (*Distribution, string, error) {
-	if len(args) < 1 {
-		return nil, "", fmt.Errorf("at least one argument is required")
+		log.Printf("dry run: %d paths would be invalidated (est. cost $%.3f)", len(paths), cf.Plan{Paths: paths}.Cost(freePaths))
+		return
 	}
 
-	id := args[0]
-	path := "/*"
-	if len(args) > 1 {
-		path = args[1]
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	dist, err := getDistribution(ctx, id)
-	if err == nil {
-		return dist, path, nil
+	batches := cf.Batch(paths)
+
+	for _, dist := range dists {
+		log.Printf("%s: %s", dist.ID, dist.Comment)
+		for _, v := range dist.Aliases {
+			log.Printf(" - %s", v)
+		}
+
+		log.Printf("invalidating %d paths in %d batches", len(paths), len(batches))
+		if !wait && len(batches) > 1 {
+			log.Printf("waiting for the first %d batches anyway, since each has to finish before the next starts", len(batches)-1)
+		}
+
+		for i, batch := range batches {
+			ref := cf.CallerReference(callerRef, i, len(batches))
+
+			inv, existed, err := cf.CreateInvalidation(ctx, cloudfront, dist.ID, ref, batch)
+			if err != nil {
+				log.Fatalf("couldn't invalidate distribution: %s", err)
+				return
+			}
+
+			invalidation := inv.ID
+			switch {
+			case !existed:
+				log.Printf("invalidation created: %s (batch %d of %d, %d paths)", invalidation, i+1, len(batches), len(batch))
+			case inv.SamePaths(batch):
+				log.Printf("invalidation already exists for caller reference %s: %s (%s)", ref, invalidation, inv.Status)
+			default:
+				log.Printf("warning: invalidation %s already exists for caller reference %s with different paths (%d instead of %d); not invalidating again", invalidation, ref, len(inv.Paths), len(batch))
+			}
+
+			// The ID alone goes to stdout so CI steps can capture it; with
+			// several distributions, it's prefixed by the distribution ID.
+			if len(dists) > 1 {
+				fmt.Printf("%s\t%s\n", dist.ID, invalidation)
+			} else {
+				fmt.Println(invalidation)
+			}
+
+			// Invalidations are limited in how many paths can be in progress
+			// at once, so each batch has to finish before the next one
+			// starts.
+			if !wait && i == len(batches)-1 {
+				break
+			}
+
+			if err := cf.WaitForInvalidation(waitCtx, cloudfront, dist.ID, invalidation, log.Printf); err != nil {
+				log.Printf("couldn't wait for invalidation %s: %s", invalidation, err)
+				os.Exit(cf.ExitCode(err))
+			}
+		}
 	}
 
-	if nsd := new(cloudfronttypes.NoSuchDistribution); !errors.As(err, &nsd) {
-		return nil, "", fmt.Errorf("get distribution: %w", err)
+	if manifest != nil {
+		by, err := manifest.Encode()
+		if err != nil {
+			log.Fatalf("couldn't encode manifest: %s", err)
+		}
+
+		if _, err := safefile.Write(manifestFile, bytes.NewReader(by), safefile.Options{Mode: 0o644, Overwrite: true}); err != nil {
+			log.Fatalf("couldn't write manifest: %s", err)
+		}
+
+		log.Printf("manifest written to %s", manifestFile)
 	}
+}
 
-	distID, err := findDistribution(ctx, id)
-	if err != nil {
-		return nil, "", fmt.Errorf("find distribution: %w", err)
+func parseArgs(ctx context.Context, selectors []string, args []string) ([]*cf.Distribution, []string, error) {
+	if len(selectors) == 0 {
+		if len(args) < 1 {
+			return nil, nil, fmt.Errorf("at least one argument is required")
+		}
+
+		selectors, args = args[:1], args[1:]
 	}
 
-	dist, err = getDistribution(ctx, distID)
-	if err != nil {
-		return nil, "", fmt.Errorf("get distribution: %w", err)
+	var dists []*cf.Distribution
+	seen := map[string]bool{}
+	for _, sel := range selectors {
+		dist, err := cf.ResolveDistribution(ctx, cloudfront, sel)
+		if err != nil {
+			return nil, nil, fmt.Errorf("resolve distribution: %w", err)
+		}
+
+		if !seen[dist.ID] {
+			seen[dist.ID] = true
+			dists = append(dists, dist)
+		}
 	}
 
-	return dist, path, nil
+	return dists, args, nil
 }
 
-func findDistribution(ctx context.Context, domain string) (string, error) {
-	dists, err := cloudfront.ListDistributions(ctx, &cloudfrontsvc.ListDistributionsInput{})
-	if err != nil {
-		return "", fmt.Errorf("list distributions: %w", err)
+type selectors []string
+
+func (s *selectors) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *selectors) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func readPathsFile(name string) ([]string, error) {
+	if name == "-" {
+		return cf.ReadPaths(os.Stdin)
 	}
 
-	for _, d := range dists.DistributionList.Items {
-		for _, a := range d.Aliases.Items {
-			if a == domain {
-				return aws.ToString(d.Id), nil
-			}
-		}
+	fp, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("os: open: %w", err)
 	}
+	defer fp.Close()
 
-	return "", fmt.Errorf("not found")
+	return cf.ReadPaths(fp)
 }
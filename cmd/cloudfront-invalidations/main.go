@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	cloudfrontsvc "github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	cf "github.com/jimmysawczuk/aws-tools/internal/cloudfront"
+)
+
+func main() {
+	var limit int
+	var status string
+	var details bool
+	var format string
+	var wait bool
+	var timeout time.Duration
+
+	flag.IntVar(&limit, "limit", 20, "the most invalidations to list (0 for all)")
+	flag.StringVar(&status, "status", "", "only list invalidations with this status, e.g. InProgress or Completed")
+	flag.BoolVar(&details, "details", false, "fetch the caller reference and paths of each listed invalidation")
+	flag.StringVar(&format, "format", "table", "output format: table or json")
+	flag.BoolVar(&wait, "wait", false, "with an invalidation ID, wait for it to complete")
+	flag.DurationVar(&timeout, "timeout", 20*time.Minute, "how long to wait for the invalidation to complete (0 for no limit)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <distribution id or alias> [invalidation id]\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if format != "table" && format != "json" {
+		log.Fatalf("unknown format: %s", format)
+	}
+
+	args := flag.Args()
+	if len(args) < 1 || len(args) > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if wait && len(args) != 2 {
+		log.Fatal("-wait requires an invalidation ID")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	cloudfront := cloudfrontsvc.NewFromConfig(cfg)
+
+	dist, err := cf.ResolveDistribution(ctx, cloudfront, args[0])
+	if err != nil {
+		log.Fatalf("couldn't resolve distribution: %s", err)
+	}
+
+	log.Printf("%s: %s", dist.ID, dist.Comment)
+
+	if len(args) == 2 {
+		id := args[1]
+
+		if wait {
+			waitCtx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				waitCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			if err := cf.WaitForInvalidation(waitCtx, cloudfront, dist.ID, id, log.Printf); err != nil {
+				log.Printf("couldn't wait for invalidation %s: %s", id, err)
+				os.Exit(cf.ExitCode(err))
+			}
+		}
+
+		inv, err := cf.GetInvalidation(ctx, cloudfront, dist.ID, id)
+		if err != nil {
+			log.Fatalf("couldn't get invalidation: %s", err)
+		}
+
+		if format == "json" {
+			encode(inv)
+			return
+		}
+
+		fmt.Printf("ID:               %s\n", inv.ID)
+		fmt.Printf("Status:           %s\n", inv.Status)
+		fmt.Printf("Created:          %s\n", timestamp(inv.CreateTime))
+		fmt.Printf("Caller reference: %s\n", inv.CallerReference)
+		fmt.Printf("Paths (%d):\n", len(inv.Paths))
+		for _, p := range inv.Paths {
+			fmt.Printf("  %s\n", p)
+		}
+		return
+	}
+
+	// The status filter is applied client-side, so list everything and
+	// apply the limit afterwards.
+	listLimit := limit
+	if status != "" {
+		listLimit = 0
+	}
+
+	all, err := cf.ListInvalidations(ctx, cloudfront, dist.ID, listLimit)
+	if err != nil {
+		log.Fatalf("couldn't list invalidations: %s", err)
+	}
+
+	var invalidations []cf.Invalidation
+	for _, inv := range all {
+		if status != "" && !strings.EqualFold(inv.Status, status) {
+			continue
+		}
+
+		if details {
+			full, err := cf.GetInvalidation(ctx, cloudfront, dist.ID, inv.ID)
+			if err != nil {
+				log.Fatalf("couldn't get invalidation: %s", err)
+			}
+			inv = *full
+		}
+
+		invalidations = append(invalidations, inv)
+		if limit > 0 && len(invalidations) == limit {
+			break
+		}
+	}
+
+	if format == "json" {
+		encode(invalidations)
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if details {
+		fmt.Fprintln(tw, "ID\tSTATUS\tCREATED\tCALLER REFERENCE\tPATHS")
+	} else {
+		fmt.Fprintln(tw, "ID\tSTATUS\tCREATED")
+	}
+	for _, inv := range invalidations {
+		if details {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", inv.ID, inv.Status, timestamp(inv.CreateTime), inv.CallerReference, summarizePaths(inv.Paths))
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", inv.ID, inv.Status, timestamp(inv.CreateTime))
+		}
+	}
+	tw.Flush()
+
+	log.Println(len(invalidations), "invalidations found")
+}
+
+func encode(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Fatal(fmt.Errorf("json: encode: %w", err))
+	}
+}
+
+// summarizePaths shows the first few paths of an invalidation so the table
+// stays readable.
+func summarizePaths(paths []string) string {
+	const shown = 3
+
+	if len(paths) <= shown {
+		return strings.Join(paths, " ")
+	}
+
+	return fmt.Sprintf("%s (+%d more)", strings.Join(paths[:shown], " "), len(paths)-shown)
+}
+
+func timestamp(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return t.Local().Format("2006-01-02 15:04:05")
+}
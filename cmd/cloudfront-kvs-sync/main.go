@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	cloudfrontsvc "github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
+	cf "github.com/jimmysawczuk/aws-tools/internal/cloudfront"
+)
+
+func main() {
+	var dryRun bool
+	var prune bool
+
+	flag.BoolVar(&dryRun, "dry-run", true, "set to false to actually write to the key value store")
+	flag.BoolVar(&prune, "delete", true, "delete keys that aren't in the file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <key value store name or ARN> <file.json or file.csv>\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	store, file := flag.Arg(0), flag.Arg(1)
+
+	desired, err := cf.ReadKVSFile(file)
+	if err != nil {
+		log.Fatalf("couldn't read %s: %s", file, err)
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	arn, err := cf.ResolveKeyValueStore(ctx, cloudfrontsvc.NewFromConfig(cfg), store)
+	if err != nil {
+		log.Fatalf("couldn't find key value store: %s", err)
+	}
+
+	kvs := cloudfrontkeyvaluestore.NewFromConfig(cfg)
+
+	current, etag, err := cf.ReadKeyValueStore(ctx, kvs, arn)
+	if err != nil {
+		log.Fatalf("couldn't read key value store: %s", err)
+	}
+
+	changes := cf.PlanKVS(current, desired, prune)
+
+	adds, updates, deletes := 0, 0, 0
+	for _, c := range changes {
+		switch c.Op {
+		case '+':
+			adds++
+			log.Printf("+ %s: %s", c.Key, c.NewValue)
+		case '~':
+			updates++
+			log.Printf("~ %s: %s -> %s", c.Key, c.OldValue, c.NewValue)
+		case '-':
+			deletes++
+			log.Printf("- %s", c.Key)
+		}
+	}
+
+	log.Printf("%s: %d keys, %d to add, %d to update, %d to delete", arn, len(current), adds, updates, deletes)
+
+	if len(changes) == 0 {
+		log.Println("already in sync")
+		return
+	}
+
+	if dryRun {
+		log.Println("dry run, pass -dry-run=false to apply")
+		return
+	}
+
+	if _, err := cf.ApplyKVS(ctx, kvs, arn, etag, changes, log.Printf); err != nil {
+		log.Fatalf("couldn't update key value store: %s", err)
+	}
+
+	log.Println("key value store updated")
+}
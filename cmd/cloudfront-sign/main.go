@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/jimmysawczuk/aws-tools/internal/cfsign"
+	"github.com/jimmysawczuk/aws-tools/internal/ssm"
+)
+
+func main() {
+	var keyPairID string
+	var privateKey string
+	var expires time.Duration
+	var expiresAt string
+	var starts string
+	var ipRange string
+	var resource string
+	var custom bool
+	var cookies bool
+	var cookieDomain string
+	var cookiePath string
+	var format string
+
+	flag.StringVar(&keyPairID, "key-pair-id", os.Getenv("CLOUDFRONT_KEY_PAIR_ID"), "ID of the CloudFront public key (or key pair) to sign with (default $CLOUDFRONT_KEY_PAIR_ID)")
+	flag.StringVar(&privateKey, "private-key", os.Getenv("CLOUDFRONT_PRIVATE_KEY"), "PEM private key file, or a secret such as secretsmanager://cdn/signing#private_key (default $CLOUDFRONT_PRIVATE_KEY)")
+	flag.DurationVar(&expires, "expires", time.Hour, "how long the signature is valid for")
+	flag.StringVar(&expiresAt, "expires-at", "", "when the signature expires (RFC 3339), instead of -expires")
+	flag.StringVar(&starts, "starts-at", "", "when the signature becomes valid (RFC 3339); implies a custom policy")
+	flag.StringVar(&ipRange, "ip-range", "", "IP address or CIDR range allowed to use the signature; implies a custom policy")
+	flag.StringVar(&resource, "resource", "", "resource the policy covers, which may contain * wildcards (default the URL); anything other than the URL implies a custom policy")
+	flag.BoolVar(&custom, "custom-policy", false, "always use a custom policy, even when a canned one would do")
+	flag.BoolVar(&cookies, "cookies", false, "print signed cookies instead of a signed URL")
+	flag.StringVar(&cookieDomain, "cookie-domain", "", "domain to set the signed cookies for")
+	flag.StringVar(&cookiePath, "cookie-path", "/", "path to set the signed cookies for")
+	flag.StringVar(&format, "format", "text", "output format: text or json")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <url>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] -cookies -resource <url pattern>\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if format != "text" && format != "json" {
+		log.Fatalf("unknown format: %s", format)
+	}
+
+	rawURL := flag.Arg(0)
+	switch {
+	case flag.NArg() > 1:
+		flag.Usage()
+		os.Exit(2)
+	case rawURL == "" && !cookies:
+		log.Fatal("a URL is required")
+	case rawURL == "" && resource == "":
+		log.Fatal("-cookies requires a URL or -resource")
+	}
+
+	if keyPairID == "" {
+		log.Fatal("-key-pair-id is required")
+	}
+
+	if privateKey == "" {
+		log.Fatal("-private-key is required")
+	}
+
+	policy := cfsign.Policy{
+		Resource: resource,
+		Expires:  time.Now().Add(expires),
+		IPRange:  ipRange,
+		Custom:   custom,
+	}
+
+	if policy.Resource == "" {
+		policy.Resource = rawURL
+	}
+
+	// SignURL does the same, but the policy kind is logged before signing.
+	if !cookies && policy.Resource != rawURL {
+		policy.Custom = true
+	}
+
+	if expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			log.Fatalf("invalid -expires-at: %s", err)
+		}
+		policy.Expires = t
+	}
+
+	if starts != "" {
+		t, err := time.Parse(time.RFC3339, starts)
+		if err != nil {
+			log.Fatalf("invalid -starts-at: %s", err)
+		}
+		policy.Starts = t
+	}
+
+	if err := policy.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	key, err := loadKey(context.Background(), privateKey)
+	if err != nil {
+		log.Fatalf("couldn't load private key: %s", err)
+	}
+
+	signer := cfsign.New(keyPairID, key)
+
+	kind := "custom"
+	if policy.Canned() {
+		kind = "canned"
+	}
+	log.Printf("signing %s with a %s policy, expiring %s", policy.Resource, kind, policy.Expires.Format(time.RFC3339))
+
+	if cookies {
+		cs, err := signer.SignCookies(policy, cookieDomain, cookiePath)
+		if err != nil {
+			log.Fatalf("couldn't sign cookies: %s", err)
+		}
+
+		if format == "json" {
+			out := map[string]string{}
+			for _, c := range cs {
+				out[c.Name] = c.Value
+			}
+			encode(out)
+			return
+		}
+
+		for _, c := range cs {
+			fmt.Printf("Set-Cookie: %s\n", c.String())
+		}
+		return
+	}
+
+	signed, err := signer.SignURL(rawURL, policy)
+	if err != nil {
+		log.Fatalf("couldn't sign URL: %s", err)
+	}
+
+	if format == "json" {
+		encode(map[string]any{
+			"url":     signed,
+			"expires": policy.Expires.UTC(),
+			"policy":  kind,
+		})
+		return
+	}
+
+	fmt.Println(signed)
+}
+
+// loadKey reads the private key from a file, or from Secrets Manager when
+// source has the secretsmanager:// scheme.
+func loadKey(ctx context.Context, source string) (*rsa.PrivateKey, error) {
+	if !strings.HasPrefix(source, ssm.SecretsManagerScheme) {
+		by, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("os: read file: %w", err)
+		}
+
+		return cfsign.ParsePrivateKey(by)
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+
+	value, err := ssm.NewFromConfig(cfg).GetSecretValue(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfsign.ParsePrivateKey([]byte(value))
+}
+
+func encode(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Fatal(fmt.Errorf("json: encode: %w", err))
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	cloudfrontsvc "github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	cf "github.com/jimmysawczuk/aws-tools/internal/cloudfront"
+)
+
+func main() {
+	var weight string
+	var header string
+	var idleTTL int
+	var maxTTL int
+	var noSticky bool
+	var enable bool
+	var disable bool
+	var promote bool
+	var showDiff bool
+	var dryRun bool
+	var wait bool
+	var timeout time.Duration
+
+	flag.StringVar(&weight, "weight", "", fmt.Sprintf("route this share of requests (0-%.2f) to the staging distribution", cf.MaxStagingWeight))
+	flag.StringVar(&header, "header", "", "route requests with this header to the staging distribution, as name=value (the name must start with "+cf.StagingHeaderPrefix+")")
+	flag.IntVar(&idleTTL, "sticky-idle-ttl", 0, fmt.Sprintf("with -weight, keep a viewer on the same distribution until it's idle for this many seconds (%d-%d, requires -sticky-max-ttl)", cf.MinStickyTTL, cf.MaxStickyTTL))
+	flag.IntVar(&maxTTL, "sticky-max-ttl", 0, fmt.Sprintf("with -weight, keep a viewer on the same distribution for at most this many seconds (%d-%d, requires -sticky-idle-ttl)", cf.MinStickyTTL, cf.MaxStickyTTL))
+	flag.BoolVar(&noSticky, "no-sticky", false, "with -weight, stop keeping viewers on the same distribution (otherwise existing sticky sessions are kept)")
+	flag.BoolVar(&enable, "enable", false, "enable the continuous deployment policy")
+	flag.BoolVar(&disable, "disable", false, "disable the continuous deployment policy, sending all traffic to the primary distribution")
+	flag.BoolVar(&promote, "promote", false, "copy the staging distribution's config to the primary distribution")
+	flag.BoolVar(&showDiff, "diff", true, "show how the staging distribution's config differs from the primary's")
+	flag.BoolVar(&dryRun, "dry-run", true, "set to false to actually change the policy or promote")
+	flag.BoolVar(&wait, "wait", true, "wait for distributions to finish deploying")
+	flag.DurationVar(&timeout, "timeout", 30*time.Minute, "how long to wait for deployment (0 for no limit)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <primary distribution id or alias>\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch {
+	case weight != "" && header != "":
+		log.Fatal("-weight and -header can't be used together")
+	case enable && disable:
+		log.Fatal("-enable and -disable can't be used together")
+	case promote && (weight != "" || header != "" || enable || disable):
+		log.Fatal("-promote can't be combined with policy changes")
+	case weight == "" && (idleTTL != 0 || maxTTL != 0 || noSticky):
+		log.Fatal("-sticky-idle-ttl, -sticky-max-ttl and -no-sticky require -weight")
+	case noSticky && (idleTTL != 0 || maxTTL != 0):
+		log.Fatal("-no-sticky can't be combined with sticky session TTLs")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	cloudfront := cloudfrontsvc.NewFromConfig(cfg)
+
+	primary, err := cf.ResolveDistribution(ctx, cloudfront, flag.Arg(0))
+	if err != nil {
+		log.Fatalf("couldn't resolve distribution: %s", err)
+	}
+
+	primaryConfig, _, err := cf.GetDistributionConfig(ctx, cloudfront, primary.ID)
+	if err != nil {
+		log.Fatalf("couldn't get distribution config: %s", err)
+	}
+
+	policyID := aws.ToString(primaryConfig.ContinuousDeploymentPolicyId)
+	if policyID == "" {
+		log.Fatalf("%s has no continuous deployment policy", primary.ID)
+	}
+
+	policy, policyETag, err := cf.GetContinuousDeploymentPolicy(ctx, cloudfront, policyID)
+	if err != nil {
+		log.Fatalf("couldn't get continuous deployment policy: %s", err)
+	}
+
+	var stagingDomains []string
+	if policy.StagingDistributionDnsNames != nil {
+		stagingDomains = policy.StagingDistributionDnsNames.Items
+	}
+
+	if len(stagingDomains) != 1 {
+		log.Fatalf("policy %s has %d staging distributions, expected 1", policyID, len(stagingDomains))
+	}
+
+	staging, err := cf.ResolveDistribution(ctx, cloudfront, stagingDomains[0])
+	if err != nil {
+		log.Fatalf("couldn't find staging distribution: %s", err)
+	}
+
+	fmt.Printf("Primary:  %s (%s, %s)\n", primary.ID, primary.DomainName, primary.Status)
+	fmt.Printf("Staging:  %s (%s, %s)\n", staging.ID, staging.DomainName, staging.Status)
+	fmt.Printf("Policy:   %s (%s)\n", policyID, enabled(aws.ToBool(policy.Enabled)))
+	fmt.Printf("Traffic:  %s\n", cf.DescribeTraffic(policy))
+
+	if showDiff {
+		printDiff(ctx, cloudfront, primary.ID, staging.ID)
+	}
+
+	waitFor := func(id string) {
+		if !wait {
+			return
+		}
+
+		waitCtx := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			waitCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		if err := cf.WaitForDistribution(waitCtx, cloudfront, id, log.Printf); err != nil {
+			log.Printf("couldn't wait for %s to deploy: %s", id, err)
+			os.Exit(cf.ExitCode(err))
+		}
+	}
+
+	if promote {
+		if staging.Status != cf.StatusDeployed && !wait && !dryRun {
+			log.Fatalf("staging distribution %s is %s; wait for it to deploy before promoting", staging.ID, staging.Status)
+		}
+
+		if dryRun {
+			log.Printf("dry run: would copy %s's config to %s; pass -dry-run=false to promote", staging.ID, primary.ID)
+			return
+		}
+
+		waitFor(staging.ID)
+
+		if _, err := cf.PromoteStaging(ctx, cloudfront, primary.ID, staging.ID); err != nil {
+			log.Fatalf("couldn't promote staging config: %s", err)
+		}
+
+		log.Printf("promoted %s's config to %s", staging.ID, primary.ID)
+		waitFor(primary.ID)
+		return
+	}
+
+	if weight == "" && header == "" && !enable && !disable {
+		return
+	}
+
+	updated := *policy
+	switch {
+	case weight != "":
+		w, err := strconv.ParseFloat(weight, 32)
+		if err != nil {
+			log.Fatalf("invalid -weight: %s", err)
+		}
+
+		if err := cf.SetStagingWeight(&updated, float32(w), int32(idleTTL), int32(maxTTL)); err != nil {
+			log.Fatalf("invalid -weight: %s", err)
+		}
+
+		if noSticky {
+			if err := cf.ClearStickySessions(&updated); err != nil {
+				log.Fatalf("invalid -no-sticky: %s", err)
+			}
+		}
+
+	case header != "":
+		name, value, ok := strings.Cut(header, "=")
+		if !ok {
+			log.Fatal("-header must be name=value")
+		}
+
+		if err := cf.SetStagingHeader(&updated, name, value); err != nil {
+			log.Fatalf("invalid -header: %s", err)
+		}
+	}
+
+	if enable || disable {
+		updated.Enabled = aws.Bool(enable)
+	}
+
+	log.Printf("policy: %s, %s -> %s, %s",
+		enabled(aws.ToBool(policy.Enabled)), cf.DescribeTraffic(policy),
+		enabled(aws.ToBool(updated.Enabled)), cf.DescribeTraffic(&updated))
+
+	if dryRun {
+		log.Println("dry run, pass -dry-run=false to update the policy")
+		return
+	}
+
+	if _, err := cf.UpdateContinuousDeploymentPolicy(ctx, cloudfront, policyID, policyETag, &updated); err != nil {
+		log.Fatalf("couldn't update policy: %s", err)
+	}
+
+	log.Printf("updated policy %s", policyID)
+
+	// The policy is part of the primary distribution's config, so changes
+	// take effect once it redeploys.
+	waitFor(primary.ID)
+}
+
+func printDiff(ctx context.Context, cloudfront *cloudfrontsvc.Client, primaryID, stagingID string) {
+	left, err := cf.ExportConfig(ctx, cloudfront, primaryID)
+	if err != nil {
+		log.Fatalf("couldn't export primary config: %s", err)
+	}
+
+	right, err := cf.ExportConfig(ctx, cloudfront, stagingID)
+	if err != nil {
+		log.Fatalf("couldn't export staging config: %s", err)
+	}
+
+	// These always differ between a primary and its staging distribution.
+	for _, k := range []string{"Staging", "ContinuousDeploymentPolicyId", "Aliases"} {
+		delete(left, k)
+		delete(right, k)
+	}
+
+	changes := cf.DiffConfigs(left, right)
+	if len(changes) == 0 {
+		fmt.Println("\nStaging config matches primary.")
+		return
+	}
+
+	fmt.Printf("\nStaging config differs from primary in %d settings:\n", len(changes))
+	cf.WriteChanges(os.Stdout, changes)
+}
+
+func enabled(b bool) string {
+	if b {
+		return "enabled"
+	}
+
+	return "disabled"
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	cloudfrontsvc "github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	cf "github.com/jimmysawczuk/aws-tools/internal/cloudfront"
+)
+
+type result struct {
+	Type           string `json:"type"`
+	DistributionID string `json:"distributionId"`
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	Done           bool   `json:"done"`
+	TimedOut       bool   `json:"timedOut"`
+	Elapsed        string `json:"elapsed"`
+	Error          string `json:"error,omitempty"`
+}
+
+func main() {
+	var timeout time.Duration
+	var interval time.Duration
+	var maxInterval time.Duration
+	var format string
+
+	flag.DurationVar(&timeout, "timeout", 30*time.Minute, "how long to wait (0 for no limit)")
+	flag.DurationVar(&interval, "interval", 0, "delay between the first checks, doubling up to -max-interval (default 5s for distributions, 2s for invalidations)")
+	flag.DurationVar(&maxInterval, "max-interval", 0, "longest delay between checks (default 60s for distributions, 30s for invalidations)")
+	flag.StringVar(&format, "format", "text", "output format: text or json")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <distribution id or alias> [invalidation id]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Waits for the distribution to be Deployed, or for the invalidation to complete.")
+		fmt.Fprintln(flag.CommandLine.Output())
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if format != "text" && format != "json" {
+		log.Fatalf("unknown format: %s", format)
+	}
+
+	args := flag.Args()
+	if len(args) < 1 || len(args) > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	cloudfront := cloudfrontsvc.NewFromConfig(cfg)
+
+	dist, err := cf.ResolveDistribution(ctx, cloudfront, args[0])
+	if err != nil {
+		log.Fatalf("couldn't resolve distribution: %s", err)
+	}
+
+	log.Printf("%s: %s", dist.ID, dist.Comment)
+
+	res := result{
+		Type:           "distribution",
+		DistributionID: dist.ID,
+		ID:             dist.ID,
+	}
+
+	opts := cf.PollOptions{
+		Interval:    5 * time.Second,
+		MaxInterval: 60 * time.Second,
+		Logf:        log.Printf,
+	}
+	check := cf.DistributionCheck(cloudfront, dist.ID)
+
+	if len(args) == 2 {
+		res.Type = "invalidation"
+		res.ID = args[1]
+
+		opts.Interval = 2 * time.Second
+		opts.MaxInterval = 30 * time.Second
+		check = cf.InvalidationCheck(cloudfront, dist.ID, res.ID)
+	}
+
+	if interval > 0 {
+		opts.Interval = interval
+	}
+	if maxInterval > 0 {
+		opts.MaxInterval = maxInterval
+	}
+
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	res.Status, err = cf.Poll(waitCtx, res.Type+" "+res.ID, opts, check)
+	res.Elapsed = time.Since(start).Round(time.Second).String()
+	res.Done = err == nil
+	res.TimedOut = errors.Is(err, cf.ErrTimeout)
+
+	if err != nil {
+		res.Error = err.Error()
+		log.Printf("couldn't wait for %s %s: %s", res.Type, res.ID, err)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			log.Fatal(fmt.Errorf("json: encode: %w", err))
+		}
+	} else {
+		fmt.Printf("%s %s: %s\n", res.Type, res.ID, status(res.Status))
+	}
+
+	os.Exit(cf.ExitCode(err))
+}
+
+func status(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+
+	return s
+}
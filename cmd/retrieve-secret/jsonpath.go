@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lookup resolves key against a decoded JSON document. A key starting with
+// "$" is treated as a JSONPath-style expression supporting dotted fields,
+// bracketed quoted fields and array indexes, e.g. $.db.hosts[0] or
+// $["api.key"]; any other key is a literal top-level field name.
+func lookup(doc any, key string) (any, error) {
+	if !strings.HasPrefix(key, "$") {
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("secret is not a JSON object")
+		}
+
+		v, ok := obj[key]
+		if !ok {
+			return nil, fmt.Errorf("key %q not found in secret", key)
+		}
+
+		return v, nil
+	}
+
+	segments, err := parsePath(key)
+	if err != nil {
+		return nil, fmt.Errorf("parse path %q: %w", key, err)
+	}
+
+	cur := doc
+	for i, seg := range segments {
+		parent := "$" + strings.Join(pathStrings(segments[:i]), "")
+		at := "$" + strings.Join(pathStrings(segments[:i+1]), "")
+
+		switch s := seg.(type) {
+		case string:
+			obj, ok := cur.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: not a JSON object", parent)
+			}
+
+			v, ok := obj[s]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found in secret", at)
+			}
+			cur = v
+
+		case int:
+			arr, ok := cur.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: not a JSON array", parent)
+			}
+
+			if s < 0 || s >= len(arr) {
+				return nil, fmt.Errorf("%s: index out of range (length %d)", at, len(arr))
+			}
+			cur = arr[s]
+		}
+	}
+
+	return cur, nil
+}
+
+func parsePath(path string) ([]any, error) {
+	rest := strings.TrimPrefix(path, "$")
+	var tbr []any
+
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			if end == 0 {
+				return nil, fmt.Errorf("empty field name")
+			}
+
+			tbr = append(tbr, rest[:end])
+			rest = rest[end:]
+
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+
+			inner := rest[1:end]
+			rest = rest[end+1:]
+
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				tbr = append(tbr, inner[1:len(inner)-1])
+				continue
+			}
+
+			n, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q", inner)
+			}
+
+			tbr = append(tbr, n)
+
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest[0])
+		}
+	}
+
+	return tbr, nil
+}
+
+func pathStrings(segments []any) []string {
+	tbr := make([]string, len(segments))
+	for i, seg := range segments {
+		switch s := seg.(type) {
+		case string:
+			tbr[i] = "." + s
+		case int:
+			tbr[i] = "[" + strconv.Itoa(s) + "]"
+		}
+	}
+
+	return tbr
+}
@@ -1,22 +1,41 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/jimmysawczuk/aws-tools/internal/safefile"
+	"github.com/jimmysawczuk/aws-tools/internal/ssm"
 )
 
 func main() {
 	var out string
+	var key string
+	var format string
+	var versionID string
+	var versionStage string
+	var metadata bool
+	var mode string
+	var force bool
 	flag.StringVar(&out, "out", "", "filename to direct output (stdout if left blank)")
+	flag.StringVar(&key, "key", "", "key (or JSONPath starting with $) to select from a JSON secret")
+	flag.StringVar(&format, "format", "raw", "output format: raw, dotenv, export or json")
+	flag.StringVar(&versionID, "version-id", "", "secret version ID to retrieve")
+	flag.StringVar(&versionStage, "version-stage", "", "staging label to retrieve, e.g. AWSPREVIOUS or AWSPENDING (AWSCURRENT if left blank)")
+	flag.BoolVar(&metadata, "metadata", false, "print version metadata (version ID, stages, created date) instead of the secret value")
+	flag.StringVar(&mode, "mode", "0600", "file mode (octal) used when writing to -out")
+	flag.BoolVar(&force, "force", false, "overwrite -out if it already exists")
 
 	flag.Parse()
 	secretName := flag.Arg(0)
@@ -30,33 +49,147 @@ func main() {
 
 	sm := secretsmanager.NewFromConfig(cfg)
 
-	rd, err := getSecret(ctx, sm, secretName)
+	sec, err := getSecret(ctx, sm, secretName, versionID, versionStage)
 	if err != nil {
 		log.Fatalf("couldn't get secret: %s", err)
 	}
 
-	var wr io.Writer = os.Stdout
-	if out != "" {
-		fp, err := os.Create(out)
+	var rd io.Reader
+	switch {
+	case metadata:
+		rd = strings.NewReader(sec.metadata())
+
+	case sec.Binary != nil:
+		if key != "" || format != "raw" {
+			log.Fatalf("couldn't transform secret: binary secrets can only be written raw")
+		}
+		rd = bytes.NewReader(sec.Binary)
+
+	default:
+		rd = strings.NewReader(sec.String)
+	}
+
+	if !metadata && (key != "" || format != "raw") {
+		rd, err = transform(rd, key, format)
 		if err != nil {
-			log.Fatalf("couldn't open file for writing: %s", fmt.Errorf("os: create: %w", err))
+			log.Fatalf("couldn't transform secret: %s", err)
+		}
+	}
+
+	if out == "" {
+		if _, err := io.Copy(os.Stdout, rd); err != nil {
+			log.Fatalf("couldn't copy to stdout: %s", err)
 		}
+		return
+	}
+
+	fm, err := safefile.ParseMode(mode)
+	if err != nil {
+		log.Fatalf("couldn't parse mode: %s", err)
+	}
 
-		wr = fp
+	n, err := safefile.Write(out, rd, safefile.Options{Mode: fm, Overwrite: force})
+	if err != nil {
+		log.Fatalf("couldn't write file: %s", safefile.ForceHint(err))
+	}
+
+	log.Printf("wrote %d bytes to %s", n, out)
+}
+
+type secret struct {
+	ARN       string
+	VersionID string
+	Stages    []string
+	Created   time.Time
+	String    string
+	Binary    []byte
+}
+
+func getSecret(ctx context.Context, sm *secretsmanager.Client, name, versionID, versionStage string) (*secret, error) {
+	resp, err := ssm.GetSecretVersion(ctx, sm, name, versionID, versionStage)
+	if err != nil {
+		return nil, err
+	}
+
+	tbr := secret{
+		ARN:       aws.ToString(resp.ARN),
+		VersionID: aws.ToString(resp.VersionId),
+		Stages:    resp.VersionStages,
+		Created:   aws.ToTime(resp.CreatedDate),
+		String:    aws.ToString(resp.SecretString),
 	}
 
-	if _, err := io.Copy(wr, rd); err != nil {
-		log.Fatalf("couldn't copy to stdout: %s", err)
+	if resp.SecretString == nil && resp.SecretBinary != nil {
+		tbr.Binary = resp.SecretBinary
 	}
+
+	return &tbr, nil
+}
+
+func (s secret) metadata() string {
+	return fmt.Sprintf("arn: %s\nversion: %s\nstages: %s\ncreated: %s\n",
+		s.ARN,
+		s.VersionID,
+		strings.Join(s.Stages, ","),
+		s.Created.Format(time.RFC3339),
+	)
 }
 
-func getSecret(ctx context.Context, sm *secretsmanager.Client, name string) (io.Reader, error) {
-	resp, err := sm.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(name),
-	})
+func transform(rd io.Reader, key string, format string) (io.Reader, error) {
+	var doc any
+	dec := json.NewDecoder(rd)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("secret is not valid JSON: %w", err)
+	}
+
+	if key != "" {
+		v, err := lookup(doc, key)
+		if err != nil {
+			return nil, err
+		}
+		doc = v
+	}
+
+	if format == "raw" {
+		if s, ok := doc.(string); ok {
+			return strings.NewReader(s), nil
+		}
+
+		by, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("json: marshal: %w", err)
+		}
+
+		return strings.NewReader(string(by)), nil
+	}
+
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s output requires a JSON object", format)
+	}
+
+	for k, v := range obj {
+		switch v.(type) {
+		case map[string]any, []any:
+			return nil, fmt.Errorf("%s output requires a flat JSON object, but key %q is nested", format, k)
+		}
+	}
+
+	by, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("json: marshal: %w", err)
+	}
+
+	params, err := ssm.ParamsFromJSON(by)
 	if err != nil {
-		return nil, fmt.Errorf("secrets manager: %w", err)
+		return nil, err
+	}
+
+	buf := &strings.Builder{}
+	if err := ssm.WriteParams(buf, params, format); err != nil {
+		return nil, err
 	}
 
-	return strings.NewReader(aws.ToString(resp.SecretString)), nil
+	return strings.NewReader(buf.String()), nil
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/jimmysawczuk/aws-tools/internal/safefile"
+	"github.com/jimmysawczuk/aws-tools/internal/secrets"
+	"github.com/jimmysawczuk/aws-tools/internal/ssm"
+)
+
+func main() {
+	var prefix string
+	var tag string
+	var format string
+	var merge bool
+	var partial bool
+	var out string
+	var mode string
+	var force bool
+
+	flag.StringVar(&prefix, "prefix", "", "also retrieve every secret whose name starts with this prefix")
+	flag.StringVar(&tag, "tag", "", "also retrieve every secret with this tag, of format key or key=value")
+	flag.StringVar(&format, "format", ssm.FormatJSON, "output format: json, dotenv or export")
+	flag.BoolVar(&merge, "merge", false, "for env output, merge JSON secret keys without prefixing them with the secret name")
+	flag.BoolVar(&partial, "partial", false, "write output even if some secrets couldn't be retrieved")
+	flag.StringVar(&out, "out", "", "filename to direct output (stdout if left blank)")
+	flag.StringVar(&mode, "mode", "0600", "file mode (octal) used when writing to -out")
+	flag.BoolVar(&force, "force", false, "overwrite -out if it already exists")
+
+	flag.Parse()
+
+	names := flag.Args()
+
+	if len(names) == 0 && prefix == "" && tag == "" {
+		log.Fatal("at least one secret name, -prefix or -tag is required")
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	sm := secretsmanager.NewFromConfig(cfg)
+
+	var filters []smtypes.Filter
+	if prefix != "" {
+		filters = append(filters, smtypes.Filter{Key: smtypes.FilterNameStringTypeName, Values: []string{prefix}})
+	}
+	if tag != "" {
+		// Batch filters can't match a tag exactly, so tagged secrets are
+		// listed, checked and then fetched by name.
+		k, v, hasValue := strings.Cut(tag, "=")
+		filters = append(filters, smtypes.Filter{Key: smtypes.FilterNameStringTypeTagKey, Values: []string{k}})
+
+		entries, err := secrets.List(ctx, sm, filters)
+		if err != nil {
+			log.Fatalf("couldn't list secrets: %s", err)
+		}
+
+		for _, e := range entries {
+			if secrets.HasTag(e.Tags, k, v, hasValue) {
+				names = append(names, aws.ToString(e.Name))
+			}
+		}
+
+		filters = nil
+	}
+
+	values, errs, err := secrets.BatchGet(ctx, sm, names, filters)
+	if err != nil {
+		log.Fatalf("couldn't get secrets: %s", err)
+	}
+
+	log.Println(len(values), "secrets retrieved")
+	for _, e := range errs {
+		log.Printf("couldn't get secret: %s", e)
+	}
+
+	if len(errs) > 0 && !partial {
+		log.Fatalf("%d secrets couldn't be retrieved", len(errs))
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+
+	buf := &bytes.Buffer{}
+	if format == ssm.FormatJSON {
+		err = writeJSON(buf, values)
+	} else {
+		err = writeEnv(buf, values, format, merge)
+	}
+	if err != nil {
+		log.Fatalf("couldn't write output: %s", err)
+	}
+
+	if out == "" {
+		if _, err := io.Copy(os.Stdout, buf); err != nil {
+			log.Fatalf("couldn't copy to stdout: %s", err)
+		}
+	} else {
+		fm, err := safefile.ParseMode(mode)
+		if err != nil {
+			log.Fatalf("couldn't parse mode: %s", err)
+		}
+
+		n, err := safefile.Write(out, buf, safefile.Options{Mode: fm, Overwrite: force})
+		if err != nil {
+			log.Fatalf("couldn't write file: %s", safefile.ForceHint(err))
+		}
+
+		log.Printf("wrote %d bytes to %s", n, out)
+	}
+
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
+
+func writeJSON(w io.Writer, values []secrets.Value) error {
+	doc := make(map[string]any, len(values))
+	for _, v := range values {
+		if v.Binary != nil {
+			doc[v.Name] = v.Binary
+			continue
+		}
+
+		if _, err := secrets.DecodeObject(v.String); err == nil {
+			doc[v.Name] = json.RawMessage(v.String)
+			continue
+		}
+
+		doc[v.Name] = v.String
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("json: encode: %w", err)
+	}
+
+	return nil
+}
+
+func writeEnv(w io.Writer, values []secrets.Value, format string, merge bool) error {
+	var params []ssm.Param
+	from := map[string]string{}
+	idx := map[string]int{}
+
+	for _, v := range values {
+		if v.Binary != nil {
+			return fmt.Errorf("%s: binary secrets can't be written as %s", v.Name, format)
+		}
+
+		prefix := envName(v.Name)
+
+		kvs, err := ssm.ParamsFromJSON([]byte(v.String))
+		if err != nil {
+			// A plain-string secret is a single variable named after it.
+			kvs = []ssm.Param{{Name: prefix, Value: v.String, Secure: true}}
+		} else if !merge {
+			for i := range kvs {
+				kvs[i].Name = prefix + "_" + kvs[i].Name
+			}
+		}
+
+		for _, kv := range kvs {
+			if !ssm.ValidEnvName(kv.Name) {
+				return fmt.Errorf("%s: %q isn't a valid environment variable name", v.Name, kv.Name)
+			}
+
+			if i, ok := idx[kv.Name]; ok {
+				log.Printf("%s: overrides %s from %s", v.Name, kv.Name, from[kv.Name])
+				params[i] = kv
+				from[kv.Name] = v.Name
+				continue
+			}
+
+			idx[kv.Name] = len(params)
+			from[kv.Name] = v.Name
+			params = append(params, kv)
+		}
+	}
+
+	return ssm.WriteParams(w, params, format)
+}
+
+func envName(secretName string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, secretName))
+}
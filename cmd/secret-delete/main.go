@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+func main() {
+	var recoveryDays int64
+	var forceDelete bool
+	var dryRun bool
+
+	flag.Int64Var(&recoveryDays, "recovery-days", 30, "number of days (7-30) the secret can be restored before it's deleted")
+	flag.BoolVar(&forceDelete, "force-delete-without-recovery", false, "delete immediately with no recovery window, including secrets already scheduled for deletion (asks for confirmation)")
+	flag.BoolVar(&dryRun, "dry-run", true, "set to false to actually delete secrets")
+
+	flag.Parse()
+
+	names := flag.Args()
+	if len(names) == 0 {
+		log.Fatal("at least one secret name is required")
+	}
+
+	if !forceDelete && (recoveryDays < 7 || recoveryDays > 30) {
+		log.Fatal("recovery-days must be between 7 and 30")
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	sm := secretsmanager.NewFromConfig(cfg)
+
+	var toDelete []string
+	for _, name := range names {
+		res, err := sm.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+			SecretId: aws.String(name),
+		})
+		if err != nil {
+			log.Fatalf("couldn't describe secret: %s", fmt.Errorf("secrets manager: describe secret (%s): %w", name, err))
+		}
+
+		// A secret that's already scheduled can still be deleted right away,
+		// but not rescheduled.
+		if res.DeletedDate != nil && !forceDelete {
+			log.Printf("%s: already scheduled for deletion (requested %s)", name, res.DeletedDate.Format("2006-01-02"))
+			continue
+		}
+
+		if forceDelete {
+			if res.DeletedDate != nil {
+				log.Printf("%s: already scheduled for deletion (requested %s), will be deleted immediately, without recovery", name, res.DeletedDate.Format("2006-01-02"))
+			} else {
+				log.Printf("%s: will be deleted immediately, without recovery", name)
+			}
+		} else {
+			log.Printf("%s: will be scheduled for deletion in %d days", name, recoveryDays)
+		}
+
+		toDelete = append(toDelete, name)
+	}
+
+	if len(toDelete) == 0 {
+		log.Println("nothing to delete")
+		return
+	}
+
+	if dryRun {
+		return
+	}
+
+	if forceDelete && !confirm(toDelete) {
+		log.Fatal("confirmation failed, nothing deleted")
+	}
+
+	for _, name := range toDelete {
+		in := &secretsmanager.DeleteSecretInput{
+			SecretId: aws.String(name),
+		}
+
+		if forceDelete {
+			in.ForceDeleteWithoutRecovery = aws.Bool(true)
+		} else {
+			in.RecoveryWindowInDays = aws.Int64(recoveryDays)
+		}
+
+		res, err := sm.DeleteSecret(ctx, in)
+		if err != nil {
+			log.Fatalf("couldn't delete secret: %s", fmt.Errorf("secrets manager: delete secret (%s): %w", name, err))
+		}
+
+		log.Printf("%s: will be deleted on %s", name, aws.ToTime(res.DeletionDate).Format("2006-01-02 15:04:05 MST"))
+	}
+}
+
+func confirm(names []string) bool {
+	fmt.Fprintf(os.Stderr, "This will permanently delete %d secret(s) with no recovery. Type %q to continue: ", len(names), "delete")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return false
+	}
+
+	return strings.TrimSpace(line) == "delete"
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/jimmysawczuk/aws-tools/internal/ssm"
+)
+
+// Exit codes follow diff(1): 0 if the sources agree, 1 if they differ and 2
+// if they couldn't be compared.
+const (
+	exitSame      = 0
+	exitDifferent = 1
+	exitError     = 2
+)
+
+func main() {
+	var verbose bool
+	var showHashes bool
+
+	flag.BoolVar(&verbose, "v", false, "also list keys that are identical")
+	flag.BoolVar(&showHashes, "hashes", false, "show a short SHA-256 of each differing value")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <source> [<source>]\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "sources are SSM paths (/app/prod) or secrets (secretsmanager://app/prod, optionally with ?stage=AWSPREVIOUS or ?version=<id>).\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "with a single secret source, AWSCURRENT is compared against AWSPREVIOUS.\n\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	a, b := flag.Arg(0), flag.Arg(1)
+	switch {
+	case flag.NArg() == 1 && strings.HasPrefix(a, ssm.SecretsManagerScheme) && !strings.Contains(a, "?"):
+		a, b = a+"?stage=AWSPREVIOUS", a+"?stage=AWSCURRENT"
+	case flag.NArg() != 2:
+		flag.Usage()
+		os.Exit(exitError)
+	}
+
+	for _, src := range []string{a, b} {
+		if _, _, err := ssm.ParseSource(src); err != nil {
+			log.Printf("invalid source %s: %s", src, err)
+			os.Exit(exitError)
+		}
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Printf("unable to load AWS config: %v", err)
+		os.Exit(exitError)
+	}
+
+	cl := ssm.NewFromConfig(cfg)
+
+	left, err := cl.GetParameters(ctx, a)
+	if err != nil {
+		log.Printf("couldn't read %s: %s", a, err)
+		os.Exit(exitError)
+	}
+
+	right, err := cl.GetParameters(ctx, b)
+	if err != nil {
+		log.Printf("couldn't read %s: %s", b, err)
+		os.Exit(exitError)
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", a, b)
+
+	changes := diff(left, right)
+	for _, c := range changes {
+		if c.Op == ' ' && !verbose {
+			continue
+		}
+
+		line := fmt.Sprintf("%c %s", c.Op, c.Name)
+		if showHashes && c.Op != ' ' {
+			line += fmt.Sprintf(" (%s -> %s)", c.Left, c.Right)
+		}
+
+		fmt.Println(line)
+	}
+
+	if differing(changes) > 0 {
+		log.Printf("%d of %d keys differ", differing(changes), len(changes))
+		os.Exit(exitDifferent)
+	}
+
+	log.Printf("%d keys identical", len(changes))
+	os.Exit(exitSame)
+}
+
+type change struct {
+	Op    byte
+	Name  string
+	Left  string
+	Right string
+}
+
+// diff compares two sets of Params by the hash of their values, so values
+// never need to be printed.
+func diff(left, right []ssm.Param) []change {
+	l := hashes(left)
+	r := hashes(right)
+
+	names := map[string]bool{}
+	for k := range l {
+		names[k] = true
+	}
+	for k := range r {
+		names[k] = true
+	}
+
+	var tbr []change
+	for name := range names {
+		lh, inLeft := l[name]
+		rh, inRight := r[name]
+
+		c := change{Name: name, Left: lh, Right: rh}
+		switch {
+		case !inRight:
+			c.Op, c.Right = '-', "none"
+		case !inLeft:
+			c.Op, c.Left = '+', "none"
+		case lh != rh:
+			c.Op = '~'
+		default:
+			c.Op = ' '
+		}
+
+		tbr = append(tbr, c)
+	}
+
+	sort.Slice(tbr, func(i, j int) bool {
+		return tbr[i].Name < tbr[j].Name
+	})
+
+	return tbr
+}
+
+func hashes(params []ssm.Param) map[string]string {
+	tbr := make(map[string]string, len(params))
+	for _, p := range params {
+		sum := sha256.Sum256([]byte(p.Value))
+		tbr[p.Name] = hex.EncodeToString(sum[:])[:12]
+	}
+
+	return tbr
+}
+
+func differing(changes []change) int {
+	n := 0
+	for _, c := range changes {
+		if c.Op != ' ' {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/jimmysawczuk/aws-tools/internal/secrets"
+)
+
+func main() {
+	var namePrefix string
+	var tag string
+	var description string
+	var staleDays int
+	var staleOnly bool
+	var format string
+
+	flag.StringVar(&namePrefix, "prefix", "", "only list secrets whose name starts with this prefix")
+	flag.StringVar(&tag, "tag", "", "only list secrets with this tag, of format key or key=value")
+	flag.StringVar(&description, "description", "", "only list secrets whose description starts with this string")
+	flag.IntVar(&staleDays, "stale-days", 90, "flag secrets not accessed in this many days (0 to disable)")
+	flag.BoolVar(&staleOnly, "stale-only", false, "only list secrets flagged as stale")
+	flag.StringVar(&format, "format", "table", "output format: table or json")
+
+	flag.Parse()
+
+	if format != "table" && format != "json" {
+		log.Fatalf("unknown format: %s", format)
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	sm := secretsmanager.NewFromConfig(cfg)
+
+	tagKey, tagValue, hasValue := strings.Cut(tag, "=")
+
+	var filters []smtypes.Filter
+	if namePrefix != "" {
+		filters = append(filters, smtypes.Filter{Key: smtypes.FilterNameStringTypeName, Values: []string{namePrefix}})
+	}
+	if tagKey != "" {
+		filters = append(filters, smtypes.Filter{Key: smtypes.FilterNameStringTypeTagKey, Values: []string{tagKey}})
+	}
+	if description != "" {
+		filters = append(filters, smtypes.Filter{Key: smtypes.FilterNameStringTypeDescription, Values: []string{description}})
+	}
+
+	entries, err := secrets.List(ctx, sm, filters)
+	if err != nil {
+		log.Fatalf("couldn't list secrets: %s", err)
+	}
+
+	now := time.Now()
+
+	var list []Secret
+	for _, e := range entries {
+		if tagKey != "" && !secrets.HasTag(e.Tags, tagKey, tagValue, hasValue) {
+			continue
+		}
+
+		s := newSecret(e, now, staleDays)
+		if staleOnly && !s.Stale {
+			continue
+		}
+
+		list = append(list, s)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(list); err != nil {
+			log.Fatal(fmt.Errorf("json: encode: %w", err))
+		}
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tLAST CHANGED\tLAST ACCESSED\tROTATION\tNEXT ROTATION\tSTALE\tARN")
+	for _, s := range list {
+		rotation := "disabled"
+		if s.RotationEnabled {
+			rotation = "enabled"
+		}
+
+		stale := ""
+		if s.Stale {
+			stale = "yes"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.Name,
+			date(s.LastChanged),
+			date(s.LastAccessed),
+			rotation,
+			date(s.NextRotation),
+			stale,
+			s.ARN,
+		)
+	}
+	tw.Flush()
+
+	log.Println(len(list), "secrets found")
+}
+
+type Secret struct {
+	Name            string            `json:"name"`
+	ARN             string            `json:"arn"`
+	Description     string            `json:"description,omitempty"`
+	LastChanged     *time.Time        `json:"lastChanged,omitempty"`
+	LastAccessed    *time.Time        `json:"lastAccessed,omitempty"`
+	RotationEnabled bool              `json:"rotationEnabled"`
+	NextRotation    *time.Time        `json:"nextRotation,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	Stale           bool              `json:"stale"`
+}
+
+func newSecret(e smtypes.SecretListEntry, now time.Time, staleDays int) Secret {
+	s := Secret{
+		Name:            aws.ToString(e.Name),
+		ARN:             aws.ToString(e.ARN),
+		Description:     aws.ToString(e.Description),
+		LastChanged:     e.LastChangedDate,
+		LastAccessed:    e.LastAccessedDate,
+		RotationEnabled: aws.ToBool(e.RotationEnabled),
+		NextRotation:    e.NextRotationDate,
+	}
+
+	if len(e.Tags) > 0 {
+		s.Tags = map[string]string{}
+		for _, t := range e.Tags {
+			s.Tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+		}
+	}
+
+	if staleDays > 0 {
+		cutoff := now.AddDate(0, 0, -staleDays)
+		s.Stale = s.LastAccessed == nil || s.LastAccessed.Before(cutoff)
+	}
+
+	return s
+}
+
+func date(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/jimmysawczuk/aws-tools/internal/ssm"
+	"github.com/joho/godotenv"
+)
+
+type tags map[string]string
+
+func (t tags) String() string {
+	var parts []string
+	for k, v := range t {
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (t tags) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("tag should be of format key=value")
+	}
+	t[k] = v
+	return nil
+}
+
+func main() {
+	var name string
+	var dotenv bool
+	var kmsKeyID string
+	var description string
+	var stage string
+	var dryRun bool
+	tagged := tags{}
+
+	flag.StringVar(&name, "name", "", "name of the secret to create or update")
+	flag.BoolVar(&dotenv, "dotenv", false, "parse the input as a dotenv file and store it as a JSON object")
+	flag.StringVar(&kmsKeyID, "kms-key-id", "", "KMS key ID, ARN or alias used to encrypt the secret")
+	flag.StringVar(&description, "description", "", "description of the secret")
+	flag.StringVar(&stage, "stage", "", "staging label for the new version, e.g. AWSPENDING (AWSCURRENT if left blank)")
+	flag.Var(tagged, "tag", "tag to apply to the secret, of format key=value (repeatable)")
+	flag.BoolVar(&dryRun, "dry-run", true, "set to false to actually write to secrets manager")
+
+	flag.Parse()
+
+	if name == "" {
+		log.Fatal("name must be present")
+	}
+
+	value, err := readInput(flag.Arg(0), dotenv)
+	if err != nil {
+		log.Fatalf("couldn't read input: %s", err)
+	}
+
+	if len(value) == 0 {
+		log.Fatal("input is empty")
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	sm := secretsmanager.NewFromConfig(cfg)
+
+	current, exists, err := currentValue(ctx, sm, name)
+	if err != nil {
+		log.Fatalf("couldn't get current secret: %s", err)
+	}
+
+	changed := !exists || sha256.Sum256(current) != sha256.Sum256(value)
+
+	switch {
+	case !exists:
+		log.Printf("%s: will be created (%d bytes)", name, len(value))
+	case changed:
+		log.Printf("%s: value will change (%d bytes -> %d bytes)", name, len(current), len(value))
+	default:
+		log.Printf("%s: value unchanged", name)
+	}
+
+	if dryRun {
+		return
+	}
+
+	if !exists {
+		if err := createSecret(ctx, sm, name, value, kmsKeyID, description, tagged); err != nil {
+			log.Fatalf("couldn't create secret: %s", err)
+		}
+		log.Printf("%s: created", name)
+		return
+	}
+
+	if err := updateSecret(ctx, sm, name, kmsKeyID, description, tagged); err != nil {
+		log.Fatalf("couldn't update secret: %s", err)
+	}
+
+	if !changed && stage == "" {
+		return
+	}
+
+	versionID, err := putValue(ctx, sm, name, value, stage)
+	if err != nil {
+		log.Fatalf("couldn't put secret value: %s", err)
+	}
+
+	log.Printf("%s: new version %s", name, versionID)
+}
+
+func readInput(path string, dotenv bool) ([]byte, error) {
+	var rd io.Reader = os.Stdin
+	if path != "" && path != "-" {
+		fp, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("os: open: %w", err)
+		}
+		defer fp.Close()
+
+		rd = fp
+	}
+
+	if !dotenv {
+		by, err := io.ReadAll(rd)
+		if err != nil {
+			return nil, fmt.Errorf("read: %w", err)
+		}
+		return by, nil
+	}
+
+	env, err := godotenv.Parse(rd)
+	if err != nil {
+		return nil, fmt.Errorf("godotenv: parse: %w", err)
+	}
+
+	by, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("json: marshal: %w", err)
+	}
+
+	return by, nil
+}
+
+func currentValue(ctx context.Context, sm *secretsmanager.Client, name string) ([]byte, bool, error) {
+	res, err := ssm.GetSecretVersion(ctx, sm, name, "", "")
+	if err != nil {
+		if rnf := new(smtypes.ResourceNotFoundException); errors.As(err, &rnf) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	if res.SecretString != nil {
+		return []byte(aws.ToString(res.SecretString)), true, nil
+	}
+
+	return res.SecretBinary, true, nil
+}
+
+func createSecret(ctx context.Context, sm *secretsmanager.Client, name string, value []byte, kmsKeyID, description string, tagged tags) error {
+	in := &secretsmanager.CreateSecretInput{
+		Name: aws.String(name),
+	}
+
+	setValue(value, &in.SecretString, &in.SecretBinary)
+
+	if kmsKeyID != "" {
+		in.KmsKeyId = aws.String(kmsKeyID)
+	}
+
+	if description != "" {
+		in.Description = aws.String(description)
+	}
+
+	for k, v := range tagged {
+		in.Tags = append(in.Tags, smtypes.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	if _, err := sm.CreateSecret(ctx, in); err != nil {
+		return fmt.Errorf("secrets manager: create secret: %w", err)
+	}
+
+	return nil
+}
+
+func updateSecret(ctx context.Context, sm *secretsmanager.Client, name string, kmsKeyID, description string, tagged tags) error {
+	if kmsKeyID != "" || description != "" {
+		in := &secretsmanager.UpdateSecretInput{
+			SecretId: aws.String(name),
+		}
+
+		if kmsKeyID != "" {
+			in.KmsKeyId = aws.String(kmsKeyID)
+		}
+
+		if description != "" {
+			in.Description = aws.String(description)
+		}
+
+		if _, err := sm.UpdateSecret(ctx, in); err != nil {
+			return fmt.Errorf("secrets manager: update secret: %w", err)
+		}
+	}
+
+	if len(tagged) > 0 {
+		in := &secretsmanager.TagResourceInput{
+			SecretId: aws.String(name),
+		}
+
+		for k, v := range tagged {
+			in.Tags = append(in.Tags, smtypes.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+
+		if _, err := sm.TagResource(ctx, in); err != nil {
+			return fmt.Errorf("secrets manager: tag resource: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func putValue(ctx context.Context, sm *secretsmanager.Client, name string, value []byte, stage string) (string, error) {
+	in := &secretsmanager.PutSecretValueInput{
+		SecretId: aws.String(name),
+	}
+
+	setValue(value, &in.SecretString, &in.SecretBinary)
+
+	if stage != "" {
+		in.VersionStages = []string{stage}
+	}
+
+	res, err := sm.PutSecretValue(ctx, in)
+	if err != nil {
+		return "", fmt.Errorf("secrets manager: put secret value: %w", err)
+	}
+
+	return aws.ToString(res.VersionId), nil
+}
+
+func setValue(value []byte, str **string, bin *[]byte) {
+	if isText(value) {
+		*str = aws.String(string(value))
+		return
+	}
+
+	*bin = value
+}
+
+func isText(by []byte) bool {
+	return !bytes.ContainsRune(by, 0) && utf8.Valid(by)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/jimmysawczuk/aws-tools/internal/ssm"
+)
+
+// kmsKeys maps a replica region to the KMS key for its replica. A key given
+// without a region is used for the other regions.
+type kmsKeys map[string]string
+
+func (k kmsKeys) String() string {
+	var parts []string
+	for r, id := range k {
+		if r == "" {
+			parts = append(parts, id)
+			continue
+		}
+		parts = append(parts, r+"="+id)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (k kmsKeys) Set(s string) error {
+	// Key IDs, ARNs and aliases never contain "=".
+	r, id, ok := strings.Cut(s, "=")
+	if !ok {
+		r, id = "", s
+	}
+	if id == "" || ok && r == "" {
+		return fmt.Errorf("kms key should be of format region=key or key")
+	}
+	if _, dup := k[r]; dup {
+		return fmt.Errorf("more than one kms key for %s", regionLabel(r))
+	}
+	k[r] = id
+	return nil
+}
+
+// forRegion returns the key for region's replica, or "" to use the region's
+// aws/secretsmanager key.
+func (k kmsKeys) forRegion(region string) string {
+	if id, ok := k[region]; ok {
+		return id
+	}
+	return k[""]
+}
+
+func regionLabel(r string) string {
+	if r == "" {
+		return "every region"
+	}
+	return r
+}
+
+func main() {
+	var add string
+	var remove string
+	keys := kmsKeys{}
+	var overwrite bool
+	var verify bool
+	var dryRun bool
+
+	flag.StringVar(&add, "add", "", "comma-separated regions to replicate the secrets to")
+	flag.StringVar(&remove, "remove", "", "comma-separated regions to remove replicas from")
+	flag.Var(keys, "kms-key-id", "KMS key used to encrypt new replicas, of format region=key, or key for every region without one (repeatable; the replica region's aws/secretsmanager key if left blank)")
+	flag.BoolVar(&overwrite, "overwrite", false, "overwrite a secret with the same name in the replica region")
+	flag.BoolVar(&verify, "verify", false, "check each replica's current value matches the primary by hash")
+	flag.BoolVar(&dryRun, "dry-run", true, "set to false to actually change replication")
+
+	flag.Parse()
+
+	names := flag.Args()
+	if len(names) == 0 {
+		log.Fatal("at least one secret name is required")
+	}
+
+	addRegions := splitRegions(add)
+	removeRegions := splitRegions(remove)
+
+	for r := range keys {
+		if r != "" && !slices.Contains(addRegions, r) {
+			log.Fatalf("-kms-key-id is set for %s, which isn't in -add", r)
+		}
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	sm := secretsmanager.NewFromConfig(cfg)
+
+	for _, name := range names {
+		for _, r := range addRegions {
+			if id := keys.forRegion(r); id != "" {
+				log.Printf("%s: will replicate to %s, encrypted with %s", name, r, id)
+			} else {
+				log.Printf("%s: will replicate to %s", name, r)
+			}
+		}
+		for _, r := range removeRegions {
+			log.Printf("%s: will remove replica in %s", name, r)
+		}
+	}
+
+	if !dryRun {
+		for _, name := range names {
+			if len(addRegions) > 0 {
+				if err := addReplicas(ctx, sm, name, addRegions, keys, overwrite); err != nil {
+					log.Fatalf("couldn't add replicas: %s", err)
+				}
+			}
+
+			if len(removeRegions) > 0 {
+				if err := removeReplicas(ctx, sm, name, removeRegions); err != nil {
+					log.Fatalf("couldn't remove replicas: %s", err)
+				}
+			}
+		}
+	}
+
+	mismatched := 0
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "NAME\tREGION\tSTATUS\tMESSAGE"
+	if verify {
+		header += "\tVALUE"
+	}
+	fmt.Fprintln(tw, header)
+
+	for _, name := range names {
+		res, err := sm.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+			SecretId: aws.String(name),
+		})
+		if err != nil {
+			log.Fatalf("couldn't describe secret: %s", fmt.Errorf("secrets manager: describe secret (%s): %w", name, err))
+		}
+
+		var primary [32]byte
+		if verify {
+			primary, err = valueHash(ctx, sm, name)
+			if err != nil {
+				log.Fatalf("couldn't get primary value: %s", err)
+			}
+		}
+
+		// name may be an ARN, which only resolves in the primary region, so
+		// replicas are looked up by name.
+		for _, r := range res.ReplicationStatus {
+			region := aws.ToString(r.Region)
+			line := fmt.Sprintf("%s\t%s\t%s\t%s", name, region, r.Status, aws.ToString(r.StatusMessage))
+
+			if verify {
+				state := "match"
+				if r.Status != smtypes.StatusTypeInSync {
+					state = "-"
+				} else if replica, err := valueHash(ctx, regionClient(cfg, region), aws.ToString(res.Name)); err != nil {
+					state = "error: " + err.Error()
+					mismatched++
+				} else if replica != primary {
+					state = "MISMATCH"
+					mismatched++
+				}
+
+				line += "\t" + state
+			}
+
+			fmt.Fprintln(tw, line)
+		}
+	}
+
+	tw.Flush()
+
+	if mismatched > 0 {
+		log.Fatalf("%d replicas don't match their primary", mismatched)
+	}
+}
+
+func addReplicas(ctx context.Context, sm *secretsmanager.Client, name string, regions []string, keys kmsKeys, overwrite bool) error {
+	in := &secretsmanager.ReplicateSecretToRegionsInput{
+		SecretId:                    aws.String(name),
+		ForceOverwriteReplicaSecret: overwrite,
+	}
+
+	for _, r := range regions {
+		rr := smtypes.ReplicaRegionType{Region: aws.String(r)}
+		if id := keys.forRegion(r); id != "" {
+			rr.KmsKeyId = aws.String(id)
+		}
+
+		in.AddReplicaRegions = append(in.AddReplicaRegions, rr)
+	}
+
+	if _, err := sm.ReplicateSecretToRegions(ctx, in); err != nil {
+		return fmt.Errorf("secrets manager: replicate secret to regions (%s): %w", name, err)
+	}
+
+	return nil
+}
+
+func removeReplicas(ctx context.Context, sm *secretsmanager.Client, name string, regions []string) error {
+	if _, err := sm.RemoveRegionsFromReplication(ctx, &secretsmanager.RemoveRegionsFromReplicationInput{
+		SecretId:             aws.String(name),
+		RemoveReplicaRegions: regions,
+	}); err != nil {
+		return fmt.Errorf("secrets manager: remove regions from replication (%s): %w", name, err)
+	}
+
+	return nil
+}
+
+func valueHash(ctx context.Context, sm *secretsmanager.Client, name string) ([32]byte, error) {
+	res, err := ssm.GetSecretVersion(ctx, sm, name, "", "")
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	if res.SecretString != nil {
+		return sha256.Sum256([]byte(aws.ToString(res.SecretString))), nil
+	}
+
+	return sha256.Sum256(res.SecretBinary), nil
+}
+
+func regionClient(cfg aws.Config, region string) *secretsmanager.Client {
+	return secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		o.Region = region
+	})
+}
+
+func splitRegions(s string) []string {
+	var tbr []string
+	for _, r := range strings.Split(s, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			tbr = append(tbr, r)
+		}
+	}
+
+	return tbr
+}
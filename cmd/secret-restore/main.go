@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// minRecoveryWindow is the shortest recovery window DeleteSecret accepts.
+const minRecoveryWindow = 7 * 24 * time.Hour
+
+func main() {
+	var dryRun bool
+
+	flag.BoolVar(&dryRun, "dry-run", true, "set to false to actually restore secrets")
+
+	flag.Parse()
+
+	ctx := context.Background()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	sm := secretsmanager.NewFromConfig(cfg)
+
+	pending, err := listPendingDeletion(ctx, sm)
+	if err != nil {
+		log.Fatalf("couldn't list secrets: %s", err)
+	}
+
+	names := flag.Args()
+	if len(names) == 0 {
+		// ListSecrets only has the time deletion was requested, not the
+		// recovery window, so the scheduled date isn't known. It's at least
+		// the minimum window after the request, which is the last time the
+		// secret is sure to be restorable.
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tDELETION REQUESTED\tDELETED NO EARLIER THAN\tARN")
+		for _, s := range pending {
+			requested := aws.ToTime(s.DeletedDate)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", aws.ToString(s.Name), requested.Format("2006-01-02 15:04:05 MST"), requested.Add(minRecoveryWindow).Format("2006-01-02 15:04:05 MST"), aws.ToString(s.ARN))
+		}
+		tw.Flush()
+
+		log.Println(len(pending), "secrets pending deletion")
+		return
+	}
+
+	byName := map[string]smtypes.SecretListEntry{}
+	for _, s := range pending {
+		byName[aws.ToString(s.Name)] = s
+		byName[aws.ToString(s.ARN)] = s
+	}
+
+	for _, name := range names {
+		if _, ok := byName[name]; !ok {
+			log.Fatalf("%s: not pending deletion", name)
+		}
+
+		log.Printf("%s: will be restored", name)
+	}
+
+	if dryRun {
+		return
+	}
+
+	for _, name := range names {
+		if _, err := sm.RestoreSecret(ctx, &secretsmanager.RestoreSecretInput{
+			SecretId: aws.String(name),
+		}); err != nil {
+			log.Fatalf("couldn't restore secret: %s", fmt.Errorf("secrets manager: restore secret (%s): %w", name, err))
+		}
+
+		log.Printf("%s: restored", name)
+	}
+}
+
+func listPendingDeletion(ctx context.Context, sm *secretsmanager.Client) ([]smtypes.SecretListEntry, error) {
+	var tbr []smtypes.SecretListEntry
+
+	p := secretsmanager.NewListSecretsPaginator(sm, &secretsmanager.ListSecretsInput{
+		IncludePlannedDeletion: aws.Bool(true),
+	})
+	for p.HasMorePages() {
+		res, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("secrets manager: list secrets: %w", err)
+		}
+
+		for _, s := range res.SecretList {
+			if s.DeletedDate != nil {
+				tbr = append(tbr, s)
+			}
+		}
+	}
+
+	return tbr, nil
+}
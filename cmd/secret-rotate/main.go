@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/jimmysawczuk/aws-tools/internal/secrets"
+	"github.com/jimmysawczuk/aws-tools/internal/ssm"
+)
+
+func main() {
+	var generator string
+	var length int
+	var key string
+	var timeout time.Duration
+	var interval time.Duration
+
+	flag.StringVar(&generator, "generate", "", "rotate client-side with a generated value: password, alphanumeric or api-key (leave blank to use the secret's rotation function)")
+	flag.IntVar(&length, "length", 32, "length of the generated value (bytes of entropy for api-key)")
+	flag.StringVar(&key, "key", "", "for JSON secrets, the key whose value is replaced when rotating client-side (required for JSON object secrets)")
+	flag.DurationVar(&timeout, "timeout", 5*time.Minute, "how long to wait for the new version to be promoted")
+	flag.DurationVar(&interval, "interval", 5*time.Second, "how often to check rotation status")
+
+	flag.Parse()
+
+	name := flag.Arg(0)
+	if name == "" {
+		log.Fatal("secret name is required")
+	}
+
+	if key != "" && generator == "" {
+		log.Fatal("-key requires -generate")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("unable to load AWS config: %v", err)
+	}
+
+	sm := secretsmanager.NewFromConfig(cfg)
+
+	r := secrets.Rotator{
+		API:      sm,
+		Interval: interval,
+		Timeout:  timeout,
+		Logf:     log.Printf,
+	}
+
+	var rot *secrets.Rotation
+	if generator == "" {
+		rot, err = r.Rotate(ctx, name)
+	} else {
+		var value string
+		value, err = newValue(ctx, sm, name, generator, length, key)
+		if err != nil {
+			log.Fatalf("couldn't generate new value: %s", err)
+		}
+
+		rot, err = r.RotateWithValue(ctx, name, value)
+	}
+
+	if rot != nil {
+		log.Printf("%s: old version %s, new version %s (%s)", rot.Name, rot.OldVersion, rot.NewVersion, rot.Took.Round(time.Second))
+		for id, stages := range rot.Stages {
+			log.Printf(" - %s: %v", id, stages)
+		}
+	}
+
+	if err != nil {
+		log.Fatalf("couldn't rotate secret: %s", err)
+	}
+
+	log.Printf("%s: rotation complete", name)
+}
+
+func newValue(ctx context.Context, sm secrets.API, name, generator string, length int, key string) (string, error) {
+	gen, err := secrets.NewGenerator(generator, length)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := gen.Generate()
+	if err != nil {
+		return "", fmt.Errorf("generate: %w", err)
+	}
+
+	res, err := ssm.GetSecretVersion(ctx, sm, name, "", "")
+	if err != nil {
+		return "", err
+	}
+
+	obj, err := secrets.DecodeObject(aws.ToString(res.SecretString))
+	if key == "" {
+		if err == nil {
+			return "", fmt.Errorf("secret is a JSON object, use -key to choose the field to replace")
+		}
+
+		return value, nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := obj[key]; !ok {
+		return "", fmt.Errorf("key %q not found in secret", key)
+	}
+
+	obj[key], err = json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("json: marshal: %w", err)
+	}
+
+	by, err := json.Marshal(obj)
+	if err != nil {
+		return "", fmt.Errorf("json: marshal: %w", err)
+	}
+
+	return string(by), nil
+}
@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/config"
-	ssmsvc "github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/jimmysawczuk/aws-tools/internal/ssm"
 	"github.com/joho/godotenv"
 )
@@ -17,13 +16,13 @@ func main() {
 	var path string
 	var dryRun bool
 
-	flag.StringVar(&path, "path", "", "path prefix for ssm")
+	flag.StringVar(&path, "path", "", "path prefix for ssm (or secretsmanager://name)")
 	flag.BoolVar(&dryRun, "dry-run", true, "set to false to actually write to parameter store")
 
 	flag.Parse()
 
-	if path == "" || !strings.HasPrefix(path, "/") {
-		log.Fatal("path must be present and start with /")
+	if _, _, err := ssm.ParseSource(path); err != nil {
+		log.Fatal(err)
 	}
 
 	envPath := flag.Arg(0)
@@ -35,7 +34,7 @@ func main() {
 		log.Fatalf("unable to load AWS config: %v", err)
 	}
 
-	ssmClient := ssmsvc.NewFromConfig(cfg)
+	cl := ssm.NewFromConfig(cfg)
 
 	fp, err := os.Open(envPath)
 	if err != nil {
@@ -59,13 +58,13 @@ func main() {
 	}
 
 	if !dryRun {
-		if err := ssm.LoadParametersIntoPath(context.Background(), ssmClient, path, params); err != nil {
-			log.Fatal("ssm: load parameters into path", err)
+		if err := cl.LoadParameters(context.Background(), path, params); err != nil {
+			log.Fatal("ssm: load parameters", err)
 		}
 		return
 	}
 
 	for _, p := range params {
-		log.Println("setting", path+"/"+p.Name+":", strings.Repeat("*", len(p.Value)))
+		log.Println("setting", ssm.Location(path, p.Name)+":", strings.Repeat("*", len(p.Value)))
 	}
 }
@@ -1,25 +1,29 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
-	"fmt"
-	"io"
 	"log"
 	"os"
-	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/config"
-	ssmsvc "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/jimmysawczuk/aws-tools/internal/safefile"
 	"github.com/jimmysawczuk/aws-tools/internal/ssm"
 )
 
 func main() {
 	var path string
 	var out string
+	var format string
+	var mode string
+	var force bool
 
-	flag.StringVar(&path, "path", "", "path prefix for ssm")
+	flag.StringVar(&path, "path", "", "path prefix for ssm (or secretsmanager://name)")
 	flag.StringVar(&out, "out", "", "output (leave blank for stdout)")
+	flag.StringVar(&format, "format", ssm.FormatDotenv, "output format: dotenv, export or json")
+	flag.StringVar(&mode, "mode", "0600", "file mode (octal) used when writing to -out")
+	flag.BoolVar(&force, "force", false, "overwrite -out if it already exists")
 
 	flag.Parse()
 
@@ -30,33 +34,40 @@ func main() {
 		log.Fatalf("unable to load AWS config: %v", err)
 	}
 
-	ssmClient := ssmsvc.NewFromConfig(cfg)
+	cl := ssm.NewFromConfig(cfg)
 
-	if path == "" || !strings.HasPrefix(path, "/") {
-		log.Fatal("path must be present and start with /")
+	if _, _, err := ssm.ParseSource(path); err != nil {
+		log.Fatal(err)
 	}
 
-	params, err := ssm.GetParametersFromPath(context.Background(), ssmClient, path)
+	params, err := cl.GetParameters(context.Background(), path)
 	if err != nil {
-		log.Fatal("ssm: get parameters from path", err)
+		log.Fatal("ssm: get parameters", err)
 	}
 
 	log.Println(len(params), "parameters loaded")
 
-	var w io.Writer = os.Stdout
-
-	if out != "" {
-		fp, err := os.Create(out)
-		if err != nil {
-			log.Fatal("os: open file (write):", err)
+	if out == "" {
+		if err := ssm.WriteParams(os.Stdout, params, format); err != nil {
+			log.Fatal("write params: ", err)
 		}
+		return
+	}
 
-		defer fp.Close()
+	fm, err := safefile.ParseMode(mode)
+	if err != nil {
+		log.Fatal("parse mode: ", err)
+	}
 
-		w = fp
+	buf := &bytes.Buffer{}
+	if err := ssm.WriteParams(buf, params, format); err != nil {
+		log.Fatal("write params: ", err)
 	}
 
-	for _, v := range params {
-		fmt.Fprintf(w, "%s=%q\n", v.Name, v.Value)
+	n, err := safefile.Write(out, buf, safefile.Options{Mode: fm, Overwrite: force})
+	if err != nil {
+		log.Fatal("write file: ", safefile.ForceHint(err))
 	}
+
+	log.Printf("wrote %d bytes to %s", n, out)
 }
@@ -0,0 +1,188 @@
+package cfsign
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decode reverses Encode.
+func decode(t *testing.T, s string) []byte {
+	t.Helper()
+
+	by, err := base64.StdEncoding.DecodeString(strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(s))
+	if err != nil {
+		t.Fatalf("decode %q: %s", s, err)
+	}
+
+	return by
+}
+
+func verify(t *testing.T, key *rsa.PublicKey, policy string, sig string) {
+	t.Helper()
+
+	h := sha1.Sum([]byte(policy))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA1, h[:], decode(t, sig)); err != nil {
+		t.Errorf("signature doesn't verify against %s: %s", policy, err)
+	}
+}
+
+func TestSignURL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := New("K2JCJMDEHXQW5F", key)
+	expires := time.Unix(1900000000, 0)
+
+	tests := []struct {
+		name       string
+		url        string
+		policy     Policy
+		wantCanned bool
+	}{
+		{
+			name:       "canned",
+			url:        "https://cdn.example.com/private/report.pdf?v=2&dl=1",
+			policy:     Policy{Expires: expires},
+			wantCanned: true,
+		},
+		{
+			name:   "forced custom",
+			url:    "https://cdn.example.com/private/report.pdf",
+			policy: Policy{Expires: expires, Custom: true},
+		},
+		{
+			name: "custom with conditions",
+			url:  "https://cdn.example.com/private/report.pdf",
+			policy: Policy{
+				Resource: "https://cdn.example.com/private/*",
+				Expires:  expires,
+				Starts:   expires.Add(-time.Hour),
+				IPRange:  "192.0.2.1",
+			},
+		},
+		{
+			name:   "resource other than the URL",
+			url:    "https://cdn.example.com/private/report.pdf",
+			policy: Policy{Resource: "https://cdn.example.com/private/report.pdf?v=1", Expires: expires},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := signer.SignURL(tt.url, tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(signed, tt.url) {
+				t.Errorf("signed URL %s doesn't keep %s", signed, tt.url)
+			}
+
+			u, err := url.Parse(signed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q := u.Query()
+
+			if got := q.Get("Key-Pair-Id"); got != signer.KeyPairID {
+				t.Errorf("Key-Pair-Id: got %q, want %q", got, signer.KeyPairID)
+			}
+
+			p := tt.policy
+			if p.Resource == "" {
+				p.Resource = tt.url
+			}
+
+			if tt.wantCanned {
+				if q.Has("Policy") {
+					t.Error("canned policy shouldn't include Policy")
+				}
+
+				if got := q.Get("Expires"); got != "1900000000" {
+					t.Errorf("Expires: got %q, want 1900000000", got)
+				}
+
+				verify(t, &key.PublicKey, p.JSON(), q.Get("Signature"))
+				return
+			}
+
+			if q.Has("Expires") {
+				t.Error("custom policy shouldn't include Expires")
+			}
+
+			doc := string(decode(t, q.Get("Policy")))
+			if doc != p.JSON() {
+				t.Errorf("policy: got %s, want %s", doc, p.JSON())
+			}
+
+			verify(t, &key.PublicKey, doc, q.Get("Signature"))
+		})
+	}
+}
+
+func TestSignCookies(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := New("K2JCJMDEHXQW5F", key)
+	p := Policy{
+		Resource: "https://cdn.example.com/private/*",
+		Expires:  time.Unix(1900000000, 0),
+	}
+
+	cookies, err := signer.SignCookies(p, "cdn.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]string{}
+	for _, c := range cookies {
+		values[c.Name] = c.Value
+
+		if c.Path != "/" || c.Domain != "cdn.example.com" || !c.Secure || !c.HttpOnly {
+			t.Errorf("%s: got path %q, domain %q, secure %v, httponly %v", c.Name, c.Path, c.Domain, c.Secure, c.HttpOnly)
+		}
+	}
+
+	if got := values["CloudFront-Key-Pair-Id"]; got != signer.KeyPairID {
+		t.Errorf("CloudFront-Key-Pair-Id: got %q, want %q", got, signer.KeyPairID)
+	}
+
+	doc := string(decode(t, values["CloudFront-Policy"]))
+	if doc != p.JSON() {
+		t.Errorf("policy: got %s, want %s", doc, p.JSON())
+	}
+
+	verify(t, &key.PublicKey, doc, values["CloudFront-Signature"])
+}
+
+func TestPolicyJSON(t *testing.T) {
+	p := Policy{
+		Resource: "https://cdn.example.com/a?b=1&c=2",
+		Expires:  time.Unix(1900000000, 0),
+		IPRange:  "192.0.2.0/24",
+	}
+
+	want := `{"Statement":[{"Resource":"https://cdn.example.com/a?b=1&c=2","Condition":{"DateLessThan":{"AWS:EpochTime":1900000000},"IpAddress":{"AWS:SourceIp":"192.0.2.0/24"}}}]}`
+	if got := p.JSON(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	// 0xfb 0xff encodes to "+/8=" in standard base64.
+	if got := Encode([]byte{0xfb, 0xff}); got != "-~8_" {
+		t.Errorf("got %q, want %q", got, "-~8_")
+	}
+}
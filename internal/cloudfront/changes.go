@@ -0,0 +1,219 @@
+package cloudfront
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GitChanges returns the files changed in revRange (e.g. "v1.2.0..HEAD")
+// under subdir of the git repository at repo, relative to subdir. Renamed
+// files are reported under both their old and new names.
+func GitChanges(repo string, revRange string, subdir string) ([]string, error) {
+	args := []string{"-C", repo, "diff", "--name-only", "--no-renames", "-z"}
+	if subdir != "" {
+		args = append(args, "--relative="+strings.Trim(filepath.ToSlash(subdir), "/"))
+	}
+	args = append(args, revRange, "--")
+
+	cmd := exec.Command("git", args...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var tbr []string
+	for _, f := range strings.Split(string(out), "\x00") {
+		if f != "" {
+			tbr = append(tbr, f)
+		}
+	}
+
+	return tbr, nil
+}
+
+// Manifest maps the slash-separated path of every file in a build directory
+// to the hex SHA-256 of its contents.
+type Manifest map[string]string
+
+func BuildManifest(dir string) (Manifest, error) {
+	m := Manifest{}
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+
+		m[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+func hashFile(p string) (string, error) {
+	fp, err := os.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("os: open: %w", err)
+	}
+	defer fp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, fp); err != nil {
+		return "", fmt.Errorf("read %s: %w", p, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadManifest reads a manifest saved by a previous deploy. A missing file is
+// treated as an empty manifest.
+func ReadManifest(name string) (Manifest, error) {
+	by, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os: read file: %w", err)
+	}
+
+	m := Manifest{}
+	if err := json.Unmarshal(by, &m); err != nil {
+		return nil, fmt.Errorf("json: unmarshal manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+func (m Manifest) Encode() ([]byte, error) {
+	by, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("json: marshal manifest: %w", err)
+	}
+
+	return append(by, '\n'), nil
+}
+
+func (m Manifest) Files() []string {
+	tbr := make([]string, 0, len(m))
+	for f := range m {
+		tbr = append(tbr, f)
+	}
+
+	sort.Strings(tbr)
+	return tbr
+}
+
+// Changed returns the files that were added, modified or removed since prev,
+// sorted.
+func (m Manifest) Changed(prev Manifest) []string {
+	var tbr []string
+
+	for f, sum := range m {
+		if prev[f] != sum {
+			tbr = append(tbr, f)
+		}
+	}
+
+	for f := range prev {
+		if _, ok := m[f]; !ok {
+			tbr = append(tbr, f)
+		}
+	}
+
+	sort.Strings(tbr)
+	return tbr
+}
+
+// URLPaths maps slash-separated file paths to the URL paths they're served
+// at. An index.html is also served at its directory root, so both are
+// returned.
+func URLPaths(files []string) []string {
+	var tbr []string
+
+	for _, f := range files {
+		f = "/" + strings.TrimLeft(filepath.ToSlash(f), "/")
+		tbr = append(tbr, f)
+
+		if path.Base(f) == "index.html" {
+			dir := path.Dir(f)
+			if dir != "/" {
+				dir += "/"
+			}
+			tbr = append(tbr, dir)
+		}
+	}
+
+	return tbr
+}
+
+// CollapseDirs replaces the paths in any directory with more than threshold
+// changed paths by a single "dir/*" wildcard. A threshold of 0 disables
+// collapsing.
+func CollapseDirs(paths []string, threshold int) []string {
+	if threshold <= 0 {
+		return paths
+	}
+
+	counts := map[string]int{}
+	for _, p := range paths {
+		counts[dirOf(p)]++
+	}
+
+	seen := map[string]bool{}
+	var tbr []string
+	for _, p := range paths {
+		if d := dirOf(p); counts[d] > threshold {
+			p = d + "*"
+		}
+
+		if !seen[p] {
+			seen[p] = true
+			tbr = append(tbr, p)
+		}
+	}
+
+	return tbr
+}
+
+func dirOf(p string) string {
+	if p == "/" {
+		return "/"
+	}
+
+	p = strings.TrimSuffix(p, "/")
+
+	i := strings.LastIndex(p, "/")
+	return p[:i+1]
+}
@@ -0,0 +1,372 @@
+package cloudfront
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+)
+
+// Config is a distribution config normalized for comparison: Quantity
+// fields are dropped, {"Items": [...]} wrappers are replaced by their lists,
+// empty values (including unset enums) are removed and lists of strings are sorted. The caller
+// reference, which is unique to every distribution, is left out.
+type Config map[string]any
+
+func GetDistributionConfig(ctx context.Context, cl *cloudfront.Client, id string) (*types.DistributionConfig, string, error) {
+	resp, err := cl.GetDistributionConfig(ctx, &cloudfront.GetDistributionConfigInput{
+		Id: aws.String(id),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("aws: cloudfront: get distribution config: %w", err)
+	}
+
+	return resp.DistributionConfig, aws.ToString(resp.ETag), nil
+}
+
+func ExportConfig(ctx context.Context, cl *cloudfront.Client, id string) (Config, error) {
+	dc, _, err := GetDistributionConfig(ctx, cl, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return NormalizeConfig(dc)
+}
+
+func NormalizeConfig(dc *types.DistributionConfig) (Config, error) {
+	by, err := json.Marshal(dc)
+	if err != nil {
+		return nil, fmt.Errorf("json: marshal distribution config: %w", err)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(by, &raw); err != nil {
+		return nil, fmt.Errorf("json: unmarshal distribution config: %w", err)
+	}
+
+	delete(raw, "CallerReference")
+
+	cfg, _ := normalize(raw).(map[string]any)
+	if cfg == nil {
+		cfg = map[string]any{}
+	}
+
+	return Config(cfg), nil
+}
+
+func normalize(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, c := range t {
+			if n := normalize(c); n != nil {
+				t[k] = n
+			} else {
+				delete(t, k)
+			}
+		}
+
+		if _, ok := t["Quantity"]; ok {
+			if _, hasItems := t["Items"]; hasItems || t["Quantity"] == 0.0 {
+				delete(t, "Quantity")
+			}
+		}
+
+		if items, ok := t["Items"]; ok && len(t) == 1 {
+			return items
+		}
+
+		if len(t) == 0 {
+			return nil
+		}
+
+		return t
+
+	case []any:
+		var tbr []any
+		strs := true
+		for _, c := range t {
+			if n := normalize(c); n != nil {
+				tbr = append(tbr, n)
+				_, ok := n.(string)
+				strs = strs && ok
+			}
+		}
+
+		if len(tbr) == 0 {
+			return nil
+		}
+
+		if strs {
+			sort.Slice(tbr, func(i, j int) bool {
+				return tbr[i].(string) < tbr[j].(string)
+			})
+		}
+
+		return tbr
+
+	case string:
+		// Unset enums marshal as empty strings.
+		if t == "" {
+			return nil
+		}
+		return t
+
+	default:
+		return v
+	}
+}
+
+func (c Config) Encode() ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return nil, fmt.Errorf("json: encode config: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ReadConfig reads a config snapshot written by Config.Encode.
+func ReadConfig(name string) (Config, error) {
+	by, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("os: read file: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(by, &cfg); err != nil {
+		return nil, fmt.Errorf("json: unmarshal config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Categories of config changes, in the order they're reported.
+const (
+	CategoryBehavior    = "behaviors"
+	CategoryCachePolicy = "cache policies"
+	CategoryOrigin      = "origins"
+	CategoryOther       = "other"
+)
+
+var Categories = []string{CategoryBehavior, CategoryCachePolicy, CategoryOrigin, CategoryOther}
+
+// ConfigChange is a single setting that differs between two configs. Op is
+// '+' if only Right has it, '-' if only Left has it and '~' if both have it
+// with different values. Values are compact JSON.
+type ConfigChange struct {
+	Op       byte   `json:"-"`
+	Kind     string `json:"op"`
+	Path     string `json:"path"`
+	Category string `json:"category"`
+	Left     string `json:"left,omitempty"`
+	Right    string `json:"right,omitempty"`
+}
+
+// DiffConfigs compares two configs setting by setting. Elements of lists of
+// objects are matched by their identifying field (an origin's Id, a cache
+// behavior's PathPattern and so on) rather than their position, so an
+// inserted behavior shows up as one addition; the order of cache behaviors,
+// which sets their precedence, is compared separately.
+func DiffConfigs(left, right Config) []ConfigChange {
+	l := map[string]string{}
+	flatten("", map[string]any(left), l)
+	behaviorOrder(left, l)
+
+	r := map[string]string{}
+	flatten("", map[string]any(right), r)
+	behaviorOrder(right, r)
+
+	paths := map[string]bool{}
+	for k := range l {
+		paths[k] = true
+	}
+	for k := range r {
+		paths[k] = true
+	}
+
+	var tbr []ConfigChange
+	for p := range paths {
+		lv, inLeft := l[p]
+		rv, inRight := r[p]
+
+		c := ConfigChange{Path: p, Category: category(p), Left: lv, Right: rv}
+		switch {
+		case !inRight:
+			c.Op, c.Kind = '-', "removed"
+		case !inLeft:
+			c.Op, c.Kind = '+', "added"
+		case lv != rv:
+			c.Op, c.Kind = '~', "changed"
+		default:
+			continue
+		}
+
+		tbr = append(tbr, c)
+	}
+
+	rank := map[string]int{}
+	for i, c := range Categories {
+		rank[c] = i
+	}
+
+	sort.Slice(tbr, func(i, j int) bool {
+		if tbr[i].Category != tbr[j].Category {
+			return rank[tbr[i].Category] < rank[tbr[j].Category]
+		}
+		return tbr[i].Path < tbr[j].Path
+	})
+
+	return tbr
+}
+
+// identityKeys are the fields that identify an element of a list of objects,
+// in order of preference.
+var identityKeys = []string{"Id", "PathPattern", "EventType", "ErrorCode", "HeaderName"}
+
+// WriteChanges writes changes as returned by DiffConfigs, one per line under
+// a "# category" heading per category.
+func WriteChanges(w io.Writer, changes []ConfigChange) {
+	category := ""
+	for _, c := range changes {
+		if c.Category != category {
+			category = c.Category
+			fmt.Fprintf(w, "\n# %s\n", category)
+		}
+
+		switch c.Op {
+		case '-':
+			fmt.Fprintf(w, "- %s: %s\n", c.Path, c.Left)
+		case '+':
+			fmt.Fprintf(w, "+ %s: %s\n", c.Path, c.Right)
+		default:
+			fmt.Fprintf(w, "~ %s: %s -> %s\n", c.Path, c.Left, c.Right)
+		}
+	}
+}
+
+func flatten(prefix string, v any, out map[string]string) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, c := range t {
+			p := k
+			if prefix != "" {
+				p = prefix + "." + k
+			}
+			flatten(p, c, out)
+		}
+
+	case []any:
+		keys := elementKeys(t)
+		if keys == nil {
+			out[prefix] = compact(t)
+			return
+		}
+
+		for i, c := range t {
+			flatten(fmt.Sprintf("%s[%s]", prefix, keys[i]), c, out)
+		}
+
+	default:
+		out[prefix] = compact(t)
+	}
+}
+
+// elementKeys returns a key for each element of a list of objects, or nil if
+// the list holds scalars. Elements are keyed by their identifying field if
+// every element has a distinct one, and by index otherwise.
+func elementKeys(list []any) []string {
+	objs := make([]map[string]any, len(list))
+	for i, c := range list {
+		m, ok := c.(map[string]any)
+		if !ok {
+			return nil
+		}
+		objs[i] = m
+	}
+
+	for _, k := range identityKeys {
+		keys := make([]string, len(objs))
+		seen := map[string]bool{}
+		for i, m := range objs {
+			v, ok := m[k]
+			if !ok {
+				break
+			}
+
+			s := strings.Trim(compact(v), `"`)
+			if seen[s] {
+				break
+			}
+
+			seen[s] = true
+			keys[i] = s
+		}
+
+		if len(seen) == len(objs) {
+			return keys
+		}
+	}
+
+	keys := make([]string, len(objs))
+	for i := range objs {
+		keys[i] = fmt.Sprint(i)
+	}
+
+	return keys
+}
+
+func behaviorOrder(cfg Config, out map[string]string) {
+	list, ok := cfg["CacheBehaviors"].([]any)
+	if !ok {
+		return
+	}
+
+	var patterns []string
+	for _, c := range list {
+		if m, ok := c.(map[string]any); ok {
+			patterns = append(patterns, fmt.Sprint(m["PathPattern"]))
+		}
+	}
+
+	out["CacheBehaviors(order)"] = compact(patterns)
+}
+
+func category(path string) string {
+	last := path
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		last = path[i+1:]
+	}
+
+	switch {
+	case last == "CachePolicyId" || last == "OriginRequestPolicyId" || last == "ResponseHeadersPolicyId" ||
+		strings.Contains(path, "ForwardedValues") || strings.HasSuffix(last, "TTL"):
+		return CategoryCachePolicy
+	case strings.HasPrefix(path, "DefaultCacheBehavior") || strings.HasPrefix(path, "CacheBehaviors"):
+		return CategoryBehavior
+	case strings.HasPrefix(path, "Origins") || strings.HasPrefix(path, "OriginGroups"):
+		return CategoryOrigin
+	default:
+		return CategoryOther
+	}
+}
+
+func compact(v any) string {
+	by, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(by)
+}
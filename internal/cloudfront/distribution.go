@@ -0,0 +1,200 @@
+package cloudfront
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+)
+
+type Distribution struct {
+	ID         string
+	ARN        string
+	DomainName string
+	Status     string
+	Comment    string
+	Aliases    []string
+}
+
+func GetDistribution(ctx context.Context, cl *cloudfront.Client, id string) (*Distribution, error) {
+	resp, err := cl.GetDistribution(ctx, &cloudfront.GetDistributionInput{
+		Id: aws.String(id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws: cloudfront: get distribution: %w", err)
+	}
+
+	dist := Distribution{
+		ID:         aws.ToString(resp.Distribution.Id),
+		ARN:        aws.ToString(resp.Distribution.ARN),
+		DomainName: aws.ToString(resp.Distribution.DomainName),
+		Status:     aws.ToString(resp.Distribution.Status),
+		Comment:    aws.ToString(resp.Distribution.DistributionConfig.Comment),
+	}
+
+	if aliases := resp.Distribution.DistributionConfig.Aliases; aliases != nil {
+		dist.Aliases = append(dist.Aliases, aliases.Items...)
+	}
+
+	return &dist, nil
+}
+
+// ResolveDistribution finds a single distribution from a selector, which is
+// one of:
+//
+//   - a distribution ID
+//   - a domain, matched against aliases (including wildcard aliases such as
+//     *.example.com) and the distribution's cloudfront.net domain
+//   - tag:key=value (or tag:key), matched against the distribution's tags
+//   - comment:text, matched as a substring of the distribution's comment
+//
+// It's an error for a selector to match no distributions or more than one.
+func ResolveDistribution(ctx context.Context, cl *cloudfront.Client, selector string) (*Distribution, error) {
+	if !strings.Contains(selector, ":") && !strings.Contains(selector, ".") {
+		dist, err := GetDistribution(ctx, cl, selector)
+		if err == nil {
+			return dist, nil
+		}
+
+		if nsd := new(types.NoSuchDistribution); !errors.As(err, &nsd) {
+			return nil, err
+		}
+	}
+
+	summaries, err := listDistributions(ctx, cl)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []types.DistributionSummary
+	switch {
+	case strings.HasPrefix(selector, "tag:"):
+		key, value, hasValue := strings.Cut(strings.TrimPrefix(selector, "tag:"), "=")
+		matches, err = matchTag(ctx, cl, summaries, key, value, hasValue)
+		if err != nil {
+			return nil, err
+		}
+
+	case strings.HasPrefix(selector, "comment:"):
+		text := strings.TrimPrefix(selector, "comment:")
+		for _, d := range summaries {
+			if strings.Contains(aws.ToString(d.Comment), text) {
+				matches = append(matches, d)
+			}
+		}
+
+	default:
+		matches = matchDomain(summaries, selector)
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no distribution matches %q", selector)
+	case 1:
+		return GetDistribution(ctx, cl, aws.ToString(matches[0].Id))
+	}
+
+	ids := make([]string, len(matches))
+	for i, d := range matches {
+		ids[i] = fmt.Sprintf("%s (%s)", aws.ToString(d.Id), aws.ToString(d.Comment))
+	}
+
+	return nil, fmt.Errorf("%d distributions match %q: %s", len(matches), selector, strings.Join(ids, ", "))
+}
+
+func listDistributions(ctx context.Context, cl *cloudfront.Client) ([]types.DistributionSummary, error) {
+	var tbr []types.DistributionSummary
+
+	p := cloudfront.NewListDistributionsPaginator(cl, &cloudfront.ListDistributionsInput{})
+	for p.HasMorePages() {
+		res, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("aws: cloudfront: list distributions: %w", err)
+		}
+
+		if res.DistributionList != nil {
+			tbr = append(tbr, res.DistributionList.Items...)
+		}
+	}
+
+	return tbr, nil
+}
+
+// matchDomain prefers distributions with an exact alias, falling back to
+// wildcard aliases only if there are none.
+func matchDomain(summaries []types.DistributionSummary, domain string) []types.DistributionSummary {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	var exact, wildcard []types.DistributionSummary
+	for _, d := range summaries {
+		if strings.EqualFold(aws.ToString(d.DomainName), domain) {
+			exact = append(exact, d)
+			continue
+		}
+
+		if d.Aliases == nil {
+			continue
+		}
+
+		for _, a := range d.Aliases.Items {
+			a = strings.ToLower(a)
+			if a == domain {
+				exact = append(exact, d)
+				break
+			}
+
+			if matchWildcardAlias(a, domain) {
+				wildcard = append(wildcard, d)
+				break
+			}
+		}
+	}
+
+	if len(exact) > 0 {
+		return exact
+	}
+
+	return wildcard
+}
+
+// matchWildcardAlias reports whether domain matches an alias like
+// *.example.com, which covers exactly one extra label.
+func matchWildcardAlias(alias, domain string) bool {
+	suffix, ok := strings.CutPrefix(alias, "*.")
+	if !ok {
+		return false
+	}
+
+	label, ok := strings.CutSuffix(domain, "."+suffix)
+	return ok && label != "" && !strings.Contains(label, ".")
+}
+
+func matchTag(ctx context.Context, cl *cloudfront.Client, summaries []types.DistributionSummary, key, value string, hasValue bool) ([]types.DistributionSummary, error) {
+	var tbr []types.DistributionSummary
+
+	for _, d := range summaries {
+		res, err := cl.ListTagsForResource(ctx, &cloudfront.ListTagsForResourceInput{
+			Resource: d.ARN,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("aws: cloudfront: list tags for resource (%s): %w", aws.ToString(d.Id), err)
+		}
+
+		if res.Tags == nil {
+			continue
+		}
+
+		for _, t := range res.Tags.Items {
+			if aws.ToString(t.Key) == key && (!hasValue || aws.ToString(t.Value) == value) {
+				tbr = append(tbr, d)
+				break
+			}
+		}
+	}
+
+	return tbr, nil
+}
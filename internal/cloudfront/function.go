@@ -0,0 +1,174 @@
+package cloudfront
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+)
+
+// Function is a CloudFront Function at one stage, with the ETag needed to
+// change it.
+type Function struct {
+	Name    string
+	Stage   types.FunctionStage
+	Runtime types.FunctionRuntime
+	Comment string
+	Code    []byte
+	ETag    string
+}
+
+// GetFunction returns the function's code and config at stage, or nil if it
+// doesn't exist there.
+func GetFunction(ctx context.Context, cl *cloudfront.Client, name string, stage types.FunctionStage) (*Function, error) {
+	desc, err := cl.DescribeFunction(ctx, &cloudfront.DescribeFunctionInput{
+		Name:  aws.String(name),
+		Stage: stage,
+	})
+	if nsf := new(types.NoSuchFunctionExists); errors.As(err, &nsf) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("aws: cloudfront: describe function (%s): %w", name, err)
+	}
+
+	code, err := cl.GetFunction(ctx, &cloudfront.GetFunctionInput{
+		Name:  aws.String(name),
+		Stage: stage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws: cloudfront: get function (%s): %w", name, err)
+	}
+
+	fn := Function{
+		Name:  name,
+		Stage: stage,
+		Code:  code.FunctionCode,
+		ETag:  aws.ToString(desc.ETag),
+	}
+
+	if cfg := desc.FunctionSummary.FunctionConfig; cfg != nil {
+		fn.Runtime = cfg.Runtime
+		fn.Comment = aws.ToString(cfg.Comment)
+	}
+
+	return &fn, nil
+}
+
+// DefaultFunctionRuntime is the runtime new functions get unless one is
+// given.
+const DefaultFunctionRuntime = types.FunctionRuntimeCloudfrontJs20
+
+// FunctionConfig is the config to give a function. A nil field keeps the
+// existing function's value, or the default when creating one.
+type FunctionConfig struct {
+	Runtime *types.FunctionRuntime
+	Comment *string
+}
+
+// Resolve returns the runtime and comment c gives fn, which may be nil for a
+// function that doesn't exist yet.
+func (c FunctionConfig) Resolve(fn *Function) (types.FunctionRuntime, string) {
+	runtime, comment := DefaultFunctionRuntime, ""
+	if fn != nil {
+		runtime, comment = fn.Runtime, fn.Comment
+	}
+
+	if c.Runtime != nil {
+		runtime = *c.Runtime
+	}
+	if c.Comment != nil {
+		comment = *c.Comment
+	}
+
+	return runtime, comment
+}
+
+// Differs reports whether fn's code differs from code, or its config from
+// what cfg would set.
+func (fn *Function) Differs(code []byte, cfg FunctionConfig) bool {
+	runtime, comment := cfg.Resolve(fn)
+	return !bytes.Equal(fn.Code, code) || fn.Runtime != runtime || fn.Comment != comment
+}
+
+// PutFunction creates the function, or updates its DEVELOPMENT stage if
+// existing is set, and returns the new ETag.
+func PutFunction(ctx context.Context, cl *cloudfront.Client, existing *Function, name string, code []byte, fnCfg FunctionConfig) (string, error) {
+	runtime, comment := fnCfg.Resolve(existing)
+	cfg := &types.FunctionConfig{
+		Comment: aws.String(comment),
+		Runtime: runtime,
+	}
+
+	if existing == nil {
+		resp, err := cl.CreateFunction(ctx, &cloudfront.CreateFunctionInput{
+			Name:           aws.String(name),
+			FunctionCode:   code,
+			FunctionConfig: cfg,
+		})
+		if err != nil {
+			return "", fmt.Errorf("aws: cloudfront: create function (%s): %w", name, err)
+		}
+
+		return aws.ToString(resp.ETag), nil
+	}
+
+	resp, err := cl.UpdateFunction(ctx, &cloudfront.UpdateFunctionInput{
+		Name:           aws.String(name),
+		IfMatch:        aws.String(existing.ETag),
+		FunctionCode:   code,
+		FunctionConfig: cfg,
+	})
+	if err != nil {
+		return "", fmt.Errorf("aws: cloudfront: update function (%s): %w", name, err)
+	}
+
+	return aws.ToString(resp.ETag), nil
+}
+
+// FunctionResult is the outcome of running a function against a test event.
+type FunctionResult struct {
+	Output      string
+	Error       string
+	Logs        []string
+	Utilization string
+}
+
+// TestFunction runs the DEVELOPMENT stage of the function against event, a
+// JSON viewer request or response event.
+func TestFunction(ctx context.Context, cl *cloudfront.Client, name string, etag string, event []byte) (*FunctionResult, error) {
+	resp, err := cl.TestFunction(ctx, &cloudfront.TestFunctionInput{
+		Name:        aws.String(name),
+		IfMatch:     aws.String(etag),
+		Stage:       types.FunctionStageDevelopment,
+		EventObject: event,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws: cloudfront: test function (%s): %w", name, err)
+	}
+
+	res := resp.TestResult
+	return &FunctionResult{
+		Output:      aws.ToString(res.FunctionOutput),
+		Error:       aws.ToString(res.FunctionErrorMessage),
+		Logs:        res.FunctionExecutionLogs,
+		Utilization: aws.ToString(res.ComputeUtilization),
+	}, nil
+}
+
+// PublishFunction copies the DEVELOPMENT stage of the function to LIVE.
+func PublishFunction(ctx context.Context, cl *cloudfront.Client, name string, etag string) error {
+	_, err := cl.PublishFunction(ctx, &cloudfront.PublishFunctionInput{
+		Name:    aws.String(name),
+		IfMatch: aws.String(etag),
+	})
+	if err != nil {
+		return fmt.Errorf("aws: cloudfront: publish function (%s): %w", name, err)
+	}
+
+	return nil
+}
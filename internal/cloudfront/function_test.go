@@ -0,0 +1,52 @@
+package cloudfront
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+)
+
+func TestFunctionDiffers(t *testing.T) {
+	js10 := types.FunctionRuntimeCloudfrontJs10
+	js20 := types.FunctionRuntimeCloudfrontJs20
+	comment := "redirects"
+	empty := ""
+
+	fn := &Function{
+		Runtime: js10,
+		Comment: comment,
+		Code:    []byte("function handler(event) { return event.request; }"),
+	}
+
+	tests := []struct {
+		name string
+		code string
+		cfg  FunctionConfig
+		want bool
+	}{
+		{name: "unset config is kept", code: string(fn.Code), want: false},
+		{name: "same config", code: string(fn.Code), cfg: FunctionConfig{Runtime: &js10, Comment: &comment}, want: false},
+		{name: "code", code: "function handler(event) { return event.response; }", want: true},
+		{name: "runtime", code: string(fn.Code), cfg: FunctionConfig{Runtime: &js20}, want: true},
+		{name: "comment cleared", code: string(fn.Code), cfg: FunctionConfig{Comment: &empty}, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := fn.Differs([]byte(tt.code), tt.cfg); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFunctionConfigResolve(t *testing.T) {
+	js10 := types.FunctionRuntimeCloudfrontJs10
+	comment := "redirects"
+
+	if runtime, c := (FunctionConfig{}).Resolve(nil); runtime != DefaultFunctionRuntime || c != "" {
+		t.Errorf("new function: got %s, %q", runtime, c)
+	}
+
+	if runtime, c := (FunctionConfig{Comment: &comment}).Resolve(&Function{Runtime: js10}); runtime != js10 || c != comment {
+		t.Errorf("existing function: got %s, %q", runtime, c)
+	}
+}
@@ -0,0 +1,181 @@
+package cloudfront
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	"github.com/aws/smithy-go"
+)
+
+const StatusCompleted = "Completed"
+
+type Invalidation struct {
+	ID              string     `json:"id"`
+	Status          string     `json:"status"`
+	CreateTime      *time.Time `json:"createTime,omitempty"`
+	CallerReference string     `json:"callerReference,omitempty"`
+	Paths           []string   `json:"paths,omitempty"`
+}
+
+// ListInvalidations returns up to limit of the distribution's invalidations,
+// most recent first. A limit of 0 returns all of them. Only the ID, status
+// and create time are filled in; use GetInvalidation for the rest.
+func ListInvalidations(ctx context.Context, cl *cloudfront.Client, distID string, limit int) ([]Invalidation, error) {
+	var tbr []Invalidation
+
+	p := cloudfront.NewListInvalidationsPaginator(cl, &cloudfront.ListInvalidationsInput{
+		DistributionId: aws.String(distID),
+	})
+	for p.HasMorePages() {
+		res, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("aws: cloudfront: list invalidations: %w", err)
+		}
+
+		if res.InvalidationList == nil {
+			break
+		}
+
+		for _, s := range res.InvalidationList.Items {
+			tbr = append(tbr, Invalidation{
+				ID:         aws.ToString(s.Id),
+				Status:     aws.ToString(s.Status),
+				CreateTime: s.CreateTime,
+			})
+
+			if limit > 0 && len(tbr) == limit {
+				return tbr, nil
+			}
+		}
+	}
+
+	return tbr, nil
+}
+
+func GetInvalidation(ctx context.Context, cl *cloudfront.Client, distID string, id string) (*Invalidation, error) {
+	resp, err := cl.GetInvalidation(ctx, &cloudfront.GetInvalidationInput{
+		DistributionId: aws.String(distID),
+		Id:             aws.String(id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws: cloudfront: get invalidation: %w", err)
+	}
+
+	return newInvalidation(resp.Invalidation), nil
+}
+
+func newInvalidation(v *types.Invalidation) *Invalidation {
+	inv := Invalidation{
+		ID:         aws.ToString(v.Id),
+		Status:     aws.ToString(v.Status),
+		CreateTime: v.CreateTime,
+	}
+
+	if b := v.InvalidationBatch; b != nil {
+		inv.CallerReference = aws.ToString(b.CallerReference)
+		if b.Paths != nil {
+			inv.Paths = append(inv.Paths, b.Paths.Items...)
+		}
+	}
+
+	return &inv
+}
+
+// CreateInvalidation invalidates paths in the distribution. CloudFront
+// treats a repeated caller reference with the same paths as the same
+// invalidation, so reusing one (e.g. a commit SHA) makes retries idempotent.
+// If the caller reference was already used, the existing invalidation is
+// returned with existed set, even if its paths differ from paths.
+func CreateInvalidation(ctx context.Context, cl *cloudfront.Client, distID string, callerRef string, paths []string) (inv *Invalidation, existed bool, err error) {
+	// A repeated request with the same paths succeeds and returns the
+	// original invalidation, so it's recognized by being listed beforehand.
+	before, err := ListInvalidations(ctx, cl, distID, 0)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err := cl.CreateInvalidation(ctx, &cloudfront.CreateInvalidationInput{
+		DistributionId: aws.String(distID),
+		InvalidationBatch: &types.InvalidationBatch{
+			CallerReference: aws.String(callerRef),
+			Paths: &types.Paths{
+				Items:    paths,
+				Quantity: aws.Int32(int32(len(paths))),
+			},
+		},
+	})
+
+	var ae smithy.APIError
+	if errors.As(err, &ae) && ae.ErrorCode() == "InvalidationBatchAlreadyExists" {
+		inv, err := FindInvalidation(ctx, cl, distID, callerRef)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return inv, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("aws: cloudfront: create invalidation: %w", err)
+	}
+
+	inv = newInvalidation(resp.Invalidation)
+	existed = slices.ContainsFunc(before, func(b Invalidation) bool {
+		return b.ID == inv.ID
+	})
+
+	return inv, existed, nil
+}
+
+// CallerReference returns the caller reference for batch i of n. Without a
+// base reference, a timestamp is used, so every run creates new
+// invalidations.
+func CallerReference(base string, i, n int) string {
+	if base == "" {
+		return time.Now().Format("20060102150405.000000000")
+	}
+
+	if n == 1 {
+		return base
+	}
+
+	return fmt.Sprintf("%s-%d", base, i+1)
+}
+
+// FindInvalidation returns the distribution's invalidation with the given
+// caller reference. Listing doesn't include caller references, so each
+// invalidation is fetched in turn, most recent first.
+func FindInvalidation(ctx context.Context, cl *cloudfront.Client, distID string, callerRef string) (*Invalidation, error) {
+	all, err := ListInvalidations(ctx, cl, distID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range all {
+		inv, err := GetInvalidation(ctx, cl, distID, r.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if inv.CallerReference == callerRef {
+			return inv, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no invalidation with caller reference %q", callerRef)
+}
+
+// SamePaths reports whether the invalidation covers exactly paths.
+func (inv *Invalidation) SamePaths(paths []string) bool {
+	a := slices.Clone(inv.Paths)
+	b := slices.Clone(paths)
+	slices.Sort(a)
+	slices.Sort(b)
+
+	return slices.Equal(a, b)
+}
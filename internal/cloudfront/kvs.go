@@ -0,0 +1,257 @@
+package cloudfront
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
+	kvstypes "github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore/types"
+)
+
+// Limits on KeyValueStore contents and on a single UpdateKeys call.
+const (
+	MaxKVSKeySize    = 512
+	MaxKVSValueSize  = 1024
+	MaxKVSBatchItems = 50
+)
+
+// ResolveKeyValueStore returns the ARN of the key value store named
+// nameOrARN, which may already be an ARN.
+func ResolveKeyValueStore(ctx context.Context, cl *cloudfront.Client, nameOrARN string) (string, error) {
+	if strings.HasPrefix(nameOrARN, "arn:") {
+		return nameOrARN, nil
+	}
+
+	resp, err := cl.DescribeKeyValueStore(ctx, &cloudfront.DescribeKeyValueStoreInput{
+		Name: aws.String(nameOrARN),
+	})
+	if err != nil {
+		return "", fmt.Errorf("aws: cloudfront: describe key value store (%s): %w", nameOrARN, err)
+	}
+
+	return aws.ToString(resp.KeyValueStore.ARN), nil
+}
+
+// ReadKeyValueStore returns the store's contents along with the ETag they
+// were read at. The ETag is fetched first, so a change made while listing
+// makes the first UpdateKeys call fail rather than be silently overwritten.
+func ReadKeyValueStore(ctx context.Context, kvs *cloudfrontkeyvaluestore.Client, arn string) (map[string]string, string, error) {
+	desc, err := kvs.DescribeKeyValueStore(ctx, &cloudfrontkeyvaluestore.DescribeKeyValueStoreInput{
+		KvsARN: aws.String(arn),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("aws: cloudfront kvs: describe key value store: %w", err)
+	}
+
+	tbr := map[string]string{}
+
+	p := cloudfrontkeyvaluestore.NewListKeysPaginator(kvs, &cloudfrontkeyvaluestore.ListKeysInput{
+		KvsARN: aws.String(arn),
+	})
+	for p.HasMorePages() {
+		res, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("aws: cloudfront kvs: list keys: %w", err)
+		}
+
+		for _, item := range res.Items {
+			tbr[aws.ToString(item.Key)] = aws.ToString(item.Value)
+		}
+	}
+
+	return tbr, aws.ToString(desc.ETag), nil
+}
+
+// ReadKVSFile reads the desired contents of a key value store from a JSON
+// object or, for files ending in .csv, from key,value rows. A leading
+// key,value header row is skipped. Non-string JSON values are stored as JSON.
+func ReadKVSFile(name string) (map[string]string, error) {
+	by, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("os: read file: %w", err)
+	}
+
+	var tbr map[string]string
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		tbr, err = parseKVSCSV(bytes.NewReader(by))
+	} else {
+		tbr, err = parseKVSJSON(by)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	for k, v := range tbr {
+		switch {
+		case k == "":
+			return nil, fmt.Errorf("%s: empty key", name)
+		case len(k) > MaxKVSKeySize:
+			return nil, fmt.Errorf("%s: key %q is longer than %d bytes", name, k, MaxKVSKeySize)
+		case len(v) > MaxKVSValueSize:
+			return nil, fmt.Errorf("%s: value of %q is longer than %d bytes", name, k, MaxKVSValueSize)
+		}
+	}
+
+	return tbr, nil
+}
+
+func parseKVSJSON(by []byte) (map[string]string, error) {
+	// The object is read token by token, since unmarshalling into a map
+	// silently keeps the last of any duplicate keys.
+	dec := json.NewDecoder(bytes.NewReader(by))
+
+	if tok, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("json: decode: %w", err)
+	} else if tok != json.Delim('{') {
+		return nil, fmt.Errorf("json: expected an object")
+	}
+
+	tbr := map[string]string{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("json: decode: %w", err)
+		}
+		k := tok.(string)
+
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("json: decode %q: %w", k, err)
+		}
+
+		if _, ok := tbr[k]; ok {
+			return nil, fmt.Errorf("duplicate key %q", k)
+		}
+
+		var str string
+		if err := json.Unmarshal(v, &str); err == nil {
+			tbr[k] = str
+			continue
+		}
+
+		tbr[k] = string(v)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("json: decode: %w", err)
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("json: unexpected data after the object")
+	}
+
+	return tbr, nil
+}
+
+func parseKVSCSV(rd io.Reader) (map[string]string, error) {
+	r := csv.NewReader(rd)
+	r.FieldsPerRecord = 2
+
+	tbr := map[string]string{}
+	for i := 0; ; i++ {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("csv: read: %w", err)
+		}
+
+		if i == 0 && strings.EqualFold(rec[0], "key") && strings.EqualFold(rec[1], "value") {
+			continue
+		}
+
+		if _, ok := tbr[rec[0]]; ok {
+			return nil, fmt.Errorf("duplicate key %q", rec[0])
+		}
+
+		tbr[rec[0]] = rec[1]
+	}
+
+	return tbr, nil
+}
+
+// KVSChange is a single key to put or delete. Op is '+' for a new key, '~'
+// for a changed value and '-' for a deletion.
+type KVSChange struct {
+	Op       byte
+	Key      string
+	OldValue string
+	NewValue string
+}
+
+// PlanKVS returns the changes that turn current into desired, sorted by key.
+// Keys missing from desired are only deleted if prune is set.
+func PlanKVS(current, desired map[string]string, prune bool) []KVSChange {
+	var tbr []KVSChange
+
+	for k, v := range desired {
+		old, ok := current[k]
+		switch {
+		case !ok:
+			tbr = append(tbr, KVSChange{Op: '+', Key: k, NewValue: v})
+		case old != v:
+			tbr = append(tbr, KVSChange{Op: '~', Key: k, OldValue: old, NewValue: v})
+		}
+	}
+
+	if prune {
+		for k, v := range current {
+			if _, ok := desired[k]; !ok {
+				tbr = append(tbr, KVSChange{Op: '-', Key: k, OldValue: v})
+			}
+		}
+	}
+
+	sort.Slice(tbr, func(i, j int) bool {
+		return tbr[i].Key < tbr[j].Key
+	})
+
+	return tbr
+}
+
+// ApplyKVS writes changes to the store in batches of MaxKVSBatchItems,
+// passing each batch the ETag returned by the one before, starting from
+// etag. It returns the final ETag; on error, the ETag after the last
+// successful batch is returned along with it.
+func ApplyKVS(ctx context.Context, kvs *cloudfrontkeyvaluestore.Client, arn string, etag string, changes []KVSChange, logf func(string, ...any)) (string, error) {
+	batches := (len(changes) + MaxKVSBatchItems - 1) / MaxKVSBatchItems
+
+	for i := 0; i < len(changes); i += MaxKVSBatchItems {
+		batch := changes[i:min(i+MaxKVSBatchItems, len(changes))]
+
+		in := &cloudfrontkeyvaluestore.UpdateKeysInput{
+			KvsARN:  aws.String(arn),
+			IfMatch: aws.String(etag),
+		}
+
+		for _, c := range batch {
+			if c.Op == '-' {
+				in.Deletes = append(in.Deletes, kvstypes.DeleteKeyRequestListItem{Key: aws.String(c.Key)})
+			} else {
+				in.Puts = append(in.Puts, kvstypes.PutKeyRequestListItem{Key: aws.String(c.Key), Value: aws.String(c.NewValue)})
+			}
+		}
+
+		resp, err := kvs.UpdateKeys(ctx, in)
+		if err != nil {
+			return etag, fmt.Errorf("aws: cloudfront kvs: update keys (batch %d of %d): %w", i/MaxKVSBatchItems+1, batches, err)
+		}
+
+		etag = aws.ToString(resp.ETag)
+		logf("applied batch %d of %d (%d puts, %d deletes); store has %d keys", i/MaxKVSBatchItems+1, batches, len(in.Puts), len(in.Deletes), aws.ToInt32(resp.ItemCount))
+	}
+
+	return etag, nil
+}
@@ -0,0 +1,54 @@
+package cloudfront
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParseKVSJSON(t *testing.T) {
+	got, err := parseKVSJSON([]byte(`{"a": "1", "b": {"x": [1, 2]}, "c": 12345678901234567890, "dé": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"a":  "1",
+		"b":  `{"x": [1, 2]}`,
+		"c":  "12345678901234567890",
+		"dé": "",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	tests := map[string]string{
+		`{"a": "1", "a": "2"}`:  "duplicate key",
+		`{"a": "1", "a": 2}`:    "duplicate key",
+		`["a"]`:                 "expected an object",
+		`{"a": "1"} {"b": "2"}`: "after the object",
+		`{"a": }`:               "decode",
+		``:                      "decode",
+	}
+
+	for in, want := range tests {
+		if _, err := parseKVSJSON([]byte(in)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got error %v, want one containing %q", in, err, want)
+		}
+	}
+}
+
+func TestParseKVSCSV(t *testing.T) {
+	got, err := parseKVSCSV(strings.NewReader("key,value\na,1\nb,\"x,y\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]string{"a": "1", "b": "x,y"}; !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := parseKVSCSV(strings.NewReader("a,1\na,2\n")); err == nil || !strings.Contains(err.Error(), "duplicate key") {
+		t.Errorf("got error %v, want a duplicate key error", err)
+	}
+}
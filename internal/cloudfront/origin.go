@@ -0,0 +1,92 @@
+package cloudfront
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+)
+
+// S3Origin is the bucket behind a distribution, and the key prefix (from
+// the origin path) its files are served from.
+type S3Origin struct {
+	ID     string
+	Bucket string
+	Region string
+	Path   string
+}
+
+// GetS3Origin finds the S3 bucket that the distribution's default cache
+// behavior is served from. Both REST endpoints (bucket.s3.amazonaws.com,
+// bucket.s3.region.amazonaws.com) and website endpoints
+// (bucket.s3-website-region.amazonaws.com) are recognized.
+func GetS3Origin(ctx context.Context, cl *cloudfront.Client, distID string) (*S3Origin, error) {
+	dc, _, err := GetDistributionConfig(ctx, cl, distID)
+	if err != nil {
+		return nil, err
+	}
+
+	target := aws.ToString(dc.DefaultCacheBehavior.TargetOriginId)
+
+	if dc.Origins == nil {
+		return nil, fmt.Errorf("distribution %s has no origins", distID)
+	}
+
+	for _, o := range dc.Origins.Items {
+		if aws.ToString(o.Id) != target {
+			continue
+		}
+
+		domain := aws.ToString(o.DomainName)
+
+		bucket, region, ok := parseS3Domain(domain)
+		if !ok {
+			return nil, fmt.Errorf("default origin %s (%s) isn't an S3 bucket", target, domain)
+		}
+
+		return &S3Origin{
+			ID:     target,
+			Bucket: bucket,
+			Region: region,
+			Path:   aws.ToString(o.OriginPath),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("distribution %s has no origin %s", distID, target)
+}
+
+func parseS3Domain(domain string) (bucket string, region string, ok bool) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	rest, ok := strings.CutSuffix(domain, ".amazonaws.com")
+	if !ok {
+		return "", "", false
+	}
+
+	// Bucket names may contain dots, so find the S3 label from the right.
+	labels := strings.Split(rest, ".")
+	for i := len(labels) - 1; i > 0; i-- {
+		l := labels[i]
+		switch {
+		case l == "s3":
+			if i+1 < len(labels) {
+				region = labels[i+1]
+			}
+		case strings.HasPrefix(l, "s3-website"):
+			region = strings.TrimPrefix(strings.TrimPrefix(l, "s3-website"), "-")
+			if region == "" && i+1 < len(labels) {
+				region = labels[i+1]
+			}
+		case strings.HasPrefix(l, "s3-"):
+			region = strings.TrimPrefix(l, "s3-")
+		default:
+			continue
+		}
+
+		return strings.Join(labels[:i], "."), region, true
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,126 @@
+package cloudfront
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CloudFront accepts at most this many paths in a single invalidation, of
+// which at most MaxWildcardsPerInvalidation may end in "*".
+const (
+	MaxPathsPerInvalidation     = 3000
+	MaxWildcardsPerInvalidation = 15
+)
+
+// NormalizePath trims p, ensures it has a leading slash and URL-encodes any
+// characters CloudFront requires to be encoded. Existing percent-escapes are
+// kept as they are, so "%2F" stays distinct from "/".
+func NormalizePath(p string) string {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return ""
+	}
+
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if isPathSafe(c) || c == '%' && i+2 < len(p) && isHex(p[i+1]) && isHex(p[i+2]) {
+			sb.WriteByte(c)
+			continue
+		}
+
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+
+	return sb.String()
+}
+
+func isPathSafe(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+
+	return strings.IndexByte("-._~!$&'()*+,;=:@/", c) >= 0
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func IsWildcard(p string) bool {
+	return strings.HasSuffix(p, "*")
+}
+
+// NormalizePaths normalizes every path in in, dropping blanks and duplicates
+// while keeping the original order.
+func NormalizePaths(in []string) []string {
+	seen := map[string]bool{}
+	var tbr []string
+
+	for _, p := range in {
+		p = NormalizePath(p)
+		if p == "" || seen[p] {
+			continue
+		}
+
+		seen[p] = true
+		tbr = append(tbr, p)
+	}
+
+	return tbr
+}
+
+// ReadPaths reads one path per line from rd, ignoring blank lines and lines
+// starting with #.
+func ReadPaths(rd io.Reader) ([]string, error) {
+	var tbr []string
+
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		tbr = append(tbr, line)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read paths: %w", err)
+	}
+
+	return tbr, nil
+}
+
+// Batch splits paths into groups that each fit within a single invalidation.
+func Batch(paths []string) [][]string {
+	var tbr [][]string
+	var cur []string
+	wildcards := 0
+
+	for _, p := range paths {
+		wild := IsWildcard(p)
+		if len(cur) == MaxPathsPerInvalidation || (wild && wildcards == MaxWildcardsPerInvalidation) {
+			tbr = append(tbr, cur)
+			cur, wildcards = nil, 0
+		}
+
+		cur = append(cur, p)
+		if wild {
+			wildcards++
+		}
+	}
+
+	if len(cur) > 0 {
+		tbr = append(tbr, cur)
+	}
+
+	return tbr
+}
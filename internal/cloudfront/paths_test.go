@@ -0,0 +1,29 @@
+package cloudfront
+
+import "testing"
+
+func TestNormalizePath(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"  ":                "",
+		"index.html":        "/index.html",
+		" /a/b ":            "/a/b",
+		"/images/*":         "/images/*",
+		"/a b.html":         "/a%20b.html",
+		"/a%20b.html":       "/a%20b.html",
+		"/a%2Fb":            "/a%2Fb",
+		"/a%2fb":            "/a%2fb",
+		"/100%":             "/100%25",
+		"/100%zz":           "/100%25zz",
+		"/café":             "/caf%C3%A9",
+		"/q?x=1":            "/q%3Fx=1",
+		"/a#b":              "/a%23b",
+		"/~user/it's+(1).x": "/~user/it's+(1).x",
+	}
+
+	for in, want := range tests {
+		if got := NormalizePath(in); got != want {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package cloudfront
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// PlanOptions bounds the plan produced by PlanPaths.
+type PlanOptions struct {
+	// MaxPaths is the most paths the plan should contain. Directories are
+	// only replaced by wildcards as far as needed to get under it. 0 means
+	// no limit, so wildcards are only used where they sweep nothing extra.
+	MaxPaths int
+
+	// MinWildcardSize is the fewest changed paths a wildcard must replace.
+	MinWildcardSize int
+
+	// Universe optionally lists every path served by the distribution, so
+	// the planner knows how many unchanged paths a wildcard would sweep.
+	// Without it, a wildcard is assumed to sweep as many unchanged paths as
+	// changed ones.
+	Universe []string
+
+	// MaxCollateral is the largest share (0-1) of unchanged paths a
+	// wildcard may sweep when Universe is set. 0 means no limit.
+	MaxCollateral float64
+
+	// FreePaths is how many invalidation paths are left in the month's
+	// free tier. Paths beyond it cost PricePerPath each.
+	FreePaths int
+}
+
+// Plan is the set of paths to invalidate, with the reasoning behind each
+// wildcard it uses.
+type Plan struct {
+	Paths     []string
+	Wildcards int
+	Requested int
+	Swept     int
+	OverLimit bool
+	Reasons   []string
+}
+
+// PricePerPath is what CloudFront charges for each invalidation path beyond
+// the monthly free tier.
+const PricePerPath = 0.005
+
+func (p Plan) Cost(freeRemaining int) float64 {
+	billable := len(p.Paths) - freeRemaining
+	if billable < 0 {
+		return 0
+	}
+
+	return float64(billable) * PricePerPath
+}
+
+const inf = math.MaxInt / 2
+
+type planNode struct {
+	prefix   string
+	children map[string]*planNode
+	order    []string
+	files    []string
+	forced   bool
+	changed  int
+	known    int
+
+	// sweep[k] is the fewest paths swept (invalidated) when covering this
+	// subtree with exactly k invalidation paths, or inf if impossible.
+	sweep []int
+	// wild is true if sweep[1] is reached with a wildcard at this node.
+	wild bool
+	// picks[i][k] is how many paths child order[i] contributes when the
+	// first i+1 children and this node's own files use k paths.
+	picks [][]int
+}
+
+// PlanPaths chooses the cheapest set of explicit paths and prefix wildcards
+// covering every path in changed that stays within opts.MaxPaths and the
+// collateral limit. Among equally cheap sets, such as any that fit in
+// opts.FreePaths, it picks the one sweeping the fewest paths. Wildcards
+// already present in changed are kept.
+func PlanPaths(changed []string, opts PlanOptions) Plan {
+	changed = NormalizePaths(changed)
+
+	root := newPlanNode("/")
+	for _, p := range changed {
+		root.add(p, true)
+	}
+
+	hasUniverse := opts.Universe != nil
+	for _, p := range NormalizePaths(opts.Universe) {
+		root.add(p, false)
+	}
+
+	minSize := opts.MinWildcardSize
+	if minSize < 2 {
+		minSize = 2
+	}
+
+	root.solve(minSize, hasUniverse, opts.MaxCollateral)
+
+	k := choose(root.sweep, opts.MaxPaths, opts.FreePaths)
+
+	plan := Plan{
+		Requested: len(changed),
+		Swept:     root.sweep[k],
+	}
+	root.collect(k, hasUniverse, &plan)
+
+	sort.Strings(plan.Paths)
+	plan.OverLimit = opts.MaxPaths > 0 && len(plan.Paths) > opts.MaxPaths
+
+	return plan
+}
+
+// choose returns the path count within maxPaths with the fewest billed paths
+// beyond freePaths, then the smallest sweep, preferring fewer paths on ties.
+// If nothing fits, it returns the fewest paths possible.
+func choose(sweep []int, maxPaths, freePaths int) int {
+	billed := func(k int) int {
+		return max(k-freePaths, 0)
+	}
+
+	best := -1
+	for k, s := range sweep {
+		if s >= inf || (maxPaths > 0 && k > maxPaths) {
+			continue
+		}
+
+		if best < 0 || billed(k) < billed(best) || billed(k) == billed(best) && s < sweep[best] {
+			best = k
+		}
+	}
+
+	if best >= 0 {
+		return best
+	}
+
+	for k, s := range sweep {
+		if s < inf {
+			return k
+		}
+	}
+
+	return 0
+}
+
+func newPlanNode(prefix string) *planNode {
+	return &planNode{prefix: prefix, children: map[string]*planNode{}}
+}
+
+func (n *planNode) add(p string, changed bool) {
+	if changed {
+		n.changed++
+	} else {
+		n.known++
+	}
+
+	rest := strings.TrimPrefix(p, n.prefix)
+	if rest == "*" {
+		n.forced = n.forced || changed
+		return
+	}
+
+	dir, file, ok := strings.Cut(rest, "/")
+	if !ok {
+		if changed {
+			n.files = append(n.files, p)
+		}
+		return
+	}
+
+	child, ok := n.children[dir]
+	if !ok {
+		child = newPlanNode(n.prefix + dir + "/")
+		n.children[dir] = child
+	}
+
+	if file == "" {
+		// A directory root such as /docs/ is an explicit path in its own
+		// directory.
+		if changed {
+			child.changed++
+			child.files = append(child.files, p)
+		} else {
+			child.known++
+		}
+		return
+	}
+
+	child.add(p, changed)
+}
+
+func (n *planNode) solve(minSize int, hasUniverse bool, maxCollateral float64) {
+	for k, c := range n.children {
+		if c.changed > 0 {
+			n.order = append(n.order, k)
+		}
+	}
+	sort.Strings(n.order)
+
+	cur := make([]int, len(n.files)+1)
+	for k := range cur {
+		cur[k] = inf
+	}
+	cur[len(n.files)] = len(n.files)
+
+	for _, name := range n.order {
+		c := n.children[name]
+		c.solve(minSize, hasUniverse, maxCollateral)
+
+		next := make([]int, len(cur)+len(c.sweep)-1)
+		pick := make([]int, len(next))
+		for k := range next {
+			next[k] = inf
+		}
+
+		for a, sa := range cur {
+			if sa >= inf {
+				continue
+			}
+
+			for b, sb := range c.sweep {
+				if sb >= inf {
+					continue
+				}
+
+				if sa+sb < next[a+b] {
+					next[a+b] = sa + sb
+					pick[a+b] = b
+				}
+			}
+		}
+
+		cur = next
+		n.picks = append(n.picks, pick)
+	}
+
+	if len(cur) < 2 {
+		cur = append(cur, inf)
+	}
+
+	if n.forced {
+		for k := range cur {
+			cur[k] = inf
+		}
+	}
+
+	if n.forced || n.wildcardAllowed(minSize, hasUniverse, maxCollateral) {
+		if s := n.wildcardSweep(hasUniverse); n.forced || s < cur[1] {
+			cur[1] = s
+			n.wild = true
+		}
+	}
+
+	n.sweep = cur
+}
+
+func (n *planNode) wildcardSweep(hasUniverse bool) int {
+	if !hasUniverse {
+		return 2 * n.changed
+	}
+
+	return max(n.known, n.changed)
+}
+
+func (n *planNode) wildcardAllowed(minSize int, hasUniverse bool, maxCollateral float64) bool {
+	if n.changed < minSize {
+		return false
+	}
+
+	if !hasUniverse || maxCollateral <= 0 {
+		return true
+	}
+
+	sweep := n.wildcardSweep(true)
+	return float64(sweep-n.changed)/float64(sweep) <= maxCollateral
+}
+
+func (n *planNode) collect(k int, hasUniverse bool, plan *Plan) {
+	if k == 1 && n.wild {
+		plan.Paths = append(plan.Paths, n.prefix+"*")
+		plan.Wildcards++
+
+		switch {
+		case n.forced:
+			plan.Reasons = append(plan.Reasons, fmt.Sprintf("%s*: requested explicitly", n.prefix))
+		case hasUniverse:
+			plan.Reasons = append(plan.Reasons, fmt.Sprintf("%s*: replaces %d changed paths, sweeping %d unchanged", n.prefix, n.changed, n.wildcardSweep(true)-n.changed))
+		default:
+			plan.Reasons = append(plan.Reasons, fmt.Sprintf("%s*: replaces %d changed paths", n.prefix, n.changed))
+		}
+		return
+	}
+
+	for i := len(n.order) - 1; i >= 0; i-- {
+		b := n.picks[i][k]
+		n.children[n.order[i]].collect(b, hasUniverse, plan)
+		k -= b
+	}
+
+	plan.Paths = append(plan.Paths, n.files...)
+}
@@ -0,0 +1,133 @@
+package cloudfront
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlanPaths(t *testing.T) {
+	tests := []struct {
+		name          string
+		changed       []string
+		opts          PlanOptions
+		want          []string
+		wantWildcards int
+		wantOverLimit bool
+	}{
+		{
+			name:    "explicit paths while they're free",
+			changed: []string{"/a/1", "/a/2", "/b/1"},
+			opts:    PlanOptions{FreePaths: 3},
+			want:    []string{"/a/1", "/a/2", "/b/1"},
+		},
+		{
+			name:          "wildcard when paths are billed",
+			changed:       []string{"/a/1", "/a/2", "/b/1"},
+			opts:          PlanOptions{FreePaths: 1},
+			want:          []string{"/*"},
+			wantWildcards: 1,
+		},
+		{
+			name:    "billed paths within collateral threshold",
+			changed: []string{"/a/1", "/a/2", "/a/3", "/b/1"},
+			opts: PlanOptions{
+				Universe:      []string{"/a/1", "/a/2", "/a/3", "/a/4", "/b/1", "/b/2", "/b/3", "/c/1"},
+				MaxCollateral: 0.3,
+			},
+			want:          []string{"/a/*", "/b/1"},
+			wantWildcards: 1,
+		},
+		{
+			name:          "wildcard sweeping nothing extra",
+			changed:       []string{"/a/1", "/a/2", "/b/1"},
+			opts:          PlanOptions{Universe: []string{"/a/1", "/a/2", "/b/1", "/b/2"}, FreePaths: 1000},
+			want:          []string{"/a/*", "/b/1"},
+			wantWildcards: 1,
+		},
+		{
+			name:          "collapse to fit max paths",
+			changed:       []string{"/a/1", "/a/2", "/a/3", "/b/1"},
+			opts:          PlanOptions{MaxPaths: 2, FreePaths: 1000},
+			want:          []string{"/a/*", "/b/1"},
+			wantWildcards: 1,
+		},
+		{
+			name:          "root-level collapse",
+			changed:       []string{"/index.html", "/a/1", "/b/1"},
+			opts:          PlanOptions{MaxPaths: 1},
+			want:          []string{"/*"},
+			wantWildcards: 1,
+		},
+		{
+			name:          "requested wildcard kept",
+			changed:       []string{"/a/*", "/a/1", "/b/1"},
+			opts:          PlanOptions{FreePaths: 1000},
+			want:          []string{"/a/*", "/b/1"},
+			wantWildcards: 1,
+		},
+		{
+			name:    "within collateral threshold",
+			changed: []string{"/a/1", "/a/2", "/a/3"},
+			opts: PlanOptions{
+				MaxPaths:      1,
+				Universe:      []string{"/a/1", "/a/2", "/a/3", "/a/4", "/b/1"},
+				MaxCollateral: 0.5,
+			},
+			want:          []string{"/a/*"},
+			wantWildcards: 1,
+		},
+		{
+			name:    "over collateral threshold",
+			changed: []string{"/a/1", "/a/2", "/a/3"},
+			opts: PlanOptions{
+				MaxPaths:      1,
+				Universe:      []string{"/a/1", "/a/2", "/a/3", "/a/4", "/b/1"},
+				MaxCollateral: 0.2,
+			},
+			want:          []string{"/a/1", "/a/2", "/a/3"},
+			wantOverLimit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := PlanPaths(tt.changed, tt.opts)
+
+			if !slices.Equal(plan.Paths, tt.want) {
+				t.Errorf("paths: got %v, want %v", plan.Paths, tt.want)
+			}
+
+			if plan.Wildcards != tt.wantWildcards {
+				t.Errorf("wildcards: got %d, want %d", plan.Wildcards, tt.wantWildcards)
+			}
+
+			if plan.OverLimit != tt.wantOverLimit {
+				t.Errorf("over limit: got %v, want %v", plan.OverLimit, tt.wantOverLimit)
+			}
+
+			if len(plan.Reasons) != plan.Wildcards {
+				t.Errorf("got %d reasons for %d wildcards: %v", len(plan.Reasons), plan.Wildcards, plan.Reasons)
+			}
+		})
+	}
+}
+
+func TestPlanCost(t *testing.T) {
+	plan := Plan{Paths: []string{"/a", "/b", "/c", "/d"}}
+
+	tests := []struct {
+		free int
+		want float64
+	}{
+		{free: 1000, want: 0},
+		{free: 4, want: 0},
+		{free: 1, want: 3 * PricePerPath},
+		{free: 0, want: 4 * PricePerPath},
+	}
+
+	for _, tt := range tests {
+		if got := plan.Cost(tt.free); got != tt.want {
+			t.Errorf("Cost(%d): got %.3f, want %.3f", tt.free, got, tt.want)
+		}
+	}
+}
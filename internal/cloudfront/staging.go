@@ -0,0 +1,183 @@
+package cloudfront
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+)
+
+// Limits CloudFront places on continuous deployment traffic.
+const (
+	MaxStagingWeight    = 0.15
+	StagingHeaderPrefix = "aws-cf-cd-"
+	MinStickyTTL        = 300
+	MaxStickyTTL        = 3600
+)
+
+const StatusDeployed = "Deployed"
+
+func GetContinuousDeploymentPolicy(ctx context.Context, cl *cloudfront.Client, id string) (*types.ContinuousDeploymentPolicyConfig, string, error) {
+	resp, err := cl.GetContinuousDeploymentPolicy(ctx, &cloudfront.GetContinuousDeploymentPolicyInput{
+		Id: aws.String(id),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("aws: cloudfront: get continuous deployment policy (%s): %w", id, err)
+	}
+
+	return resp.ContinuousDeploymentPolicy.ContinuousDeploymentPolicyConfig, aws.ToString(resp.ETag), nil
+}
+
+func UpdateContinuousDeploymentPolicy(ctx context.Context, cl *cloudfront.Client, id string, etag string, cfg *types.ContinuousDeploymentPolicyConfig) (string, error) {
+	resp, err := cl.UpdateContinuousDeploymentPolicy(ctx, &cloudfront.UpdateContinuousDeploymentPolicyInput{
+		Id:                               aws.String(id),
+		IfMatch:                          aws.String(etag),
+		ContinuousDeploymentPolicyConfig: cfg,
+	})
+	if err != nil {
+		return "", fmt.Errorf("aws: cloudfront: update continuous deployment policy (%s): %w", id, err)
+	}
+
+	return aws.ToString(resp.ETag), nil
+}
+
+// SetStagingWeight routes weight (0-MaxStagingWeight) of viewer requests to
+// the staging distribution. With idleTTL and maxTTL set (in seconds), a
+// viewer keeps going to the same distribution for the session; they must be
+// set together, between MinStickyTTL and MaxStickyTTL, with idleTTL no more
+// than maxTTL. With neither set, any stickiness the policy already has is
+// kept; ClearStickySessions removes it.
+func SetStagingWeight(cfg *types.ContinuousDeploymentPolicyConfig, weight float32, idleTTL, maxTTL int32) error {
+	if weight < 0 || weight > MaxStagingWeight {
+		return fmt.Errorf("weight must be between 0 and %.2f", MaxStagingWeight)
+	}
+
+	if idleTTL > 0 || maxTTL > 0 {
+		switch {
+		case idleTTL <= 0 || maxTTL <= 0:
+			return errors.New("sticky sessions need both an idle TTL and a max TTL")
+		case idleTTL < MinStickyTTL || idleTTL > MaxStickyTTL || maxTTL < MinStickyTTL || maxTTL > MaxStickyTTL:
+			return fmt.Errorf("sticky session TTLs must be between %d and %d seconds", MinStickyTTL, MaxStickyTTL)
+		case idleTTL > maxTTL:
+			return fmt.Errorf("sticky idle TTL (%ds) can't be more than the max TTL (%ds)", idleTTL, maxTTL)
+		}
+	}
+
+	wc := &types.ContinuousDeploymentSingleWeightConfig{
+		Weight: aws.Float32(weight),
+	}
+
+	switch {
+	case idleTTL > 0 || maxTTL > 0:
+		wc.SessionStickinessConfig = &types.SessionStickinessConfig{
+			IdleTTL:    aws.Int32(idleTTL),
+			MaximumTTL: aws.Int32(maxTTL),
+		}
+
+	case cfg.TrafficConfig != nil && cfg.TrafficConfig.SingleWeightConfig != nil:
+		wc.SessionStickinessConfig = cfg.TrafficConfig.SingleWeightConfig.SessionStickinessConfig
+	}
+
+	cfg.TrafficConfig = &types.TrafficConfig{
+		Type:               types.ContinuousDeploymentPolicyTypeSingleWeight,
+		SingleWeightConfig: wc,
+	}
+
+	return nil
+}
+
+// ClearStickySessions stops a weight-based policy from keeping viewers on
+// the same distribution.
+func ClearStickySessions(cfg *types.ContinuousDeploymentPolicyConfig) error {
+	tc := cfg.TrafficConfig
+	if tc == nil || tc.SingleWeightConfig == nil {
+		return errors.New("sticky sessions only apply to weight-based policies")
+	}
+
+	wc := *tc.SingleWeightConfig
+	wc.SessionStickinessConfig = nil
+
+	cfg.TrafficConfig = &types.TrafficConfig{
+		Type:               tc.Type,
+		SingleWeightConfig: &wc,
+	}
+
+	return nil
+}
+
+// SetStagingHeader routes viewer requests carrying header: value to the
+// staging distribution.
+func SetStagingHeader(cfg *types.ContinuousDeploymentPolicyConfig, header string, value string) error {
+	if !strings.HasPrefix(strings.ToLower(header), StagingHeaderPrefix) {
+		return fmt.Errorf("header %q must start with %s", header, StagingHeaderPrefix)
+	}
+
+	cfg.TrafficConfig = &types.TrafficConfig{
+		Type: types.ContinuousDeploymentPolicyTypeSingleHeader,
+		SingleHeaderConfig: &types.ContinuousDeploymentSingleHeaderConfig{
+			Header: aws.String(header),
+			Value:  aws.String(value),
+		},
+	}
+
+	return nil
+}
+
+// DescribeTraffic summarizes how a policy splits traffic.
+func DescribeTraffic(cfg *types.ContinuousDeploymentPolicyConfig) string {
+	tc := cfg.TrafficConfig
+	if tc == nil {
+		return "none"
+	}
+
+	switch tc.Type {
+	case types.ContinuousDeploymentPolicyTypeSingleWeight:
+		if tc.SingleWeightConfig == nil {
+			break
+		}
+
+		s := fmt.Sprintf("%.1f%% of requests", aws.ToFloat32(tc.SingleWeightConfig.Weight)*100)
+		if sc := tc.SingleWeightConfig.SessionStickinessConfig; sc != nil {
+			s += fmt.Sprintf(", sticky for %ds idle / %ds max", aws.ToInt32(sc.IdleTTL), aws.ToInt32(sc.MaximumTTL))
+		}
+		return s
+
+	case types.ContinuousDeploymentPolicyTypeSingleHeader:
+		if tc.SingleHeaderConfig == nil {
+			break
+		}
+
+		return fmt.Sprintf("requests with %s: %s", aws.ToString(tc.SingleHeaderConfig.Header), aws.ToString(tc.SingleHeaderConfig.Value))
+	}
+
+	return string(tc.Type)
+}
+
+// PromoteStaging copies the staging distribution's config to the primary
+// distribution, returning the primary's new ETag.
+func PromoteStaging(ctx context.Context, cl *cloudfront.Client, primaryID string, stagingID string) (string, error) {
+	_, primaryETag, err := GetDistributionConfig(ctx, cl, primaryID)
+	if err != nil {
+		return "", err
+	}
+
+	_, stagingETag, err := GetDistributionConfig(ctx, cl, stagingID)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := cl.UpdateDistributionWithStagingConfig(ctx, &cloudfront.UpdateDistributionWithStagingConfigInput{
+		Id:                    aws.String(primaryID),
+		StagingDistributionId: aws.String(stagingID),
+		IfMatch:               aws.String(primaryETag + ", " + stagingETag),
+	})
+	if err != nil {
+		return "", fmt.Errorf("aws: cloudfront: update distribution with staging config (%s): %w", primaryID, err)
+	}
+
+	return aws.ToString(resp.ETag), nil
+}
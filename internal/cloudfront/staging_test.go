@@ -0,0 +1,80 @@
+package cloudfront
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+)
+
+func stickyPolicy(idle, maximum int32) *types.ContinuousDeploymentPolicyConfig {
+	return &types.ContinuousDeploymentPolicyConfig{
+		TrafficConfig: &types.TrafficConfig{
+			Type: types.ContinuousDeploymentPolicyTypeSingleWeight,
+			SingleWeightConfig: &types.ContinuousDeploymentSingleWeightConfig{
+				Weight: aws.Float32(0.05),
+				SessionStickinessConfig: &types.SessionStickinessConfig{
+					IdleTTL:    aws.Int32(idle),
+					MaximumTTL: aws.Int32(maximum),
+				},
+			},
+		},
+	}
+}
+
+func stickyTTLs(cfg *types.ContinuousDeploymentPolicyConfig) (int32, int32, bool) {
+	sc := cfg.TrafficConfig.SingleWeightConfig.SessionStickinessConfig
+	if sc == nil {
+		return 0, 0, false
+	}
+
+	return aws.ToInt32(sc.IdleTTL), aws.ToInt32(sc.MaximumTTL), true
+}
+
+func TestSetStagingWeight(t *testing.T) {
+	cfg := stickyPolicy(300, 600)
+	if err := SetStagingWeight(cfg, 0.1, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := aws.ToFloat32(cfg.TrafficConfig.SingleWeightConfig.Weight); got != 0.1 {
+		t.Errorf("weight: got %v, want 0.1", got)
+	}
+	if idle, maximum, ok := stickyTTLs(cfg); !ok || idle != 300 || maximum != 600 {
+		t.Errorf("existing stickiness: got %d, %d, %v, want it kept", idle, maximum, ok)
+	}
+
+	if err := SetStagingWeight(cfg, 0.1, 900, 1800); err != nil {
+		t.Fatal(err)
+	}
+	if idle, maximum, _ := stickyTTLs(cfg); idle != 900 || maximum != 1800 {
+		t.Errorf("new stickiness: got %d, %d, want 900, 1800", idle, maximum)
+	}
+
+	if err := ClearStickySessions(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := stickyTTLs(cfg); ok {
+		t.Error("stickiness wasn't cleared")
+	}
+
+	header := &types.ContinuousDeploymentPolicyConfig{}
+	if err := SetStagingHeader(header, StagingHeaderPrefix+"test", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ClearStickySessions(header); err == nil {
+		t.Error("expected an error clearing stickiness on a header policy")
+	}
+
+	for _, bad := range [][3]float32{
+		{0.5, 0, 0},
+		{0.1, 300, 0},
+		{0.1, 60, 600},
+		{0.1, 300, 7200},
+		{0.1, 900, 600},
+	} {
+		if err := SetStagingWeight(stickyPolicy(300, 600), bad[0], int32(bad[1]), int32(bad[2])); err == nil {
+			t.Errorf("%v: expected an error", bad)
+		}
+	}
+}
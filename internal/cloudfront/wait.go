@@ -0,0 +1,163 @@
+package cloudfront
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+)
+
+// ErrTimeout is returned by Poll when ctx's deadline passes first.
+var ErrTimeout = errors.New("timed out")
+
+// Exit codes for commands that wait on CloudFront, so CI can tell a slow
+// operation apart from a failed one.
+const (
+	ExitFailed      = 1
+	ExitTimeout     = 3
+	ExitInterrupted = 130
+)
+
+// ExitCode returns the exit code for an error returned by Poll: ExitTimeout
+// if it timed out, ExitInterrupted if it was cancelled and ExitFailed
+// otherwise, or 0 for no error.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrTimeout):
+		return ExitTimeout
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	default:
+		return ExitFailed
+	}
+}
+
+// CheckFunc reports the current status of whatever is being waited on, and
+// whether it's reached the status being waited for.
+type CheckFunc func(ctx context.Context) (status string, done bool, err error)
+
+type PollOptions struct {
+	// Interval is the delay before the second check, doubling after each
+	// one up to MaxInterval.
+	Interval    time.Duration
+	MaxInterval time.Duration
+
+	// Logf, if set, is told about every check.
+	Logf func(string, ...any)
+}
+
+// Poll calls check until it reports done, backing off exponentially between
+// checks, and returns the last status seen. Transient errors are retried;
+// anything else is returned. If ctx is cancelled first, ctx.Err() is
+// returned, or ErrTimeout if its deadline passed.
+func Poll(ctx context.Context, what string, opts PollOptions, check CheckFunc) (string, error) {
+	logf := opts.Logf
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+
+	delay := opts.Interval
+	if delay <= 0 {
+		delay = 2 * time.Second
+	}
+
+	maxDelay := max(opts.MaxInterval, delay)
+
+	start := time.Now()
+	status := ""
+
+	for {
+		s, done, err := check(ctx)
+		elapsed := time.Since(start).Round(time.Second)
+
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// Fall through to the ctx.Done case below.
+
+		case err != nil && !IsTransient(err):
+			return status, err
+
+		case err != nil:
+			logf("couldn't get status of %s, retrying: %s", what, err)
+
+		case done:
+			logf("%s %s after %s", what, s, elapsed)
+			return s, nil
+
+		default:
+			status = s
+			logf("waiting on %s (%s, %s elapsed)", what, s, elapsed)
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return status, fmt.Errorf("%s: %w after %s", what, ErrTimeout, time.Since(start).Round(time.Second))
+			}
+			return status, ctx.Err()
+
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// IsTransient reports whether err is a throttle or other error the SDK
+// would retry.
+func IsTransient(err error) bool {
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary ||
+		retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary
+}
+
+// InvalidationCheck is done once the invalidation has completed.
+func InvalidationCheck(cl *cloudfront.Client, distID string, id string) CheckFunc {
+	return func(ctx context.Context) (string, bool, error) {
+		inv, err := GetInvalidation(ctx, cl, distID, id)
+		if err != nil {
+			return "", false, err
+		}
+
+		return inv.Status, inv.Status == StatusCompleted, nil
+	}
+}
+
+// DistributionCheck is done once the distribution has deployed.
+func DistributionCheck(cl *cloudfront.Client, id string) CheckFunc {
+	return func(ctx context.Context) (string, bool, error) {
+		dist, err := GetDistribution(ctx, cl, id)
+		if err != nil {
+			return "", false, err
+		}
+
+		return dist.Status, dist.Status == StatusDeployed, nil
+	}
+}
+
+// WaitForInvalidation polls until the invalidation completes.
+func WaitForInvalidation(ctx context.Context, cl *cloudfront.Client, distID string, id string, logf func(string, ...any)) error {
+	_, err := Poll(ctx, "invalidation "+id, PollOptions{
+		Interval:    2 * time.Second,
+		MaxInterval: 30 * time.Second,
+		Logf:        logf,
+	}, InvalidationCheck(cl, distID, id))
+
+	return err
+}
+
+// WaitForDistribution polls until the distribution's status is Deployed.
+func WaitForDistribution(ctx context.Context, cl *cloudfront.Client, id string, logf func(string, ...any)) error {
+	_, err := Poll(ctx, "distribution "+id, PollOptions{
+		Interval:    5 * time.Second,
+		MaxInterval: 60 * time.Second,
+		Logf:        logf,
+	}, DistributionCheck(cl, id))
+
+	return err
+}
@@ -0,0 +1,94 @@
+package s3sync
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// CacheRule sets the Cache-Control header of every file matching Pattern.
+type CacheRule struct {
+	Pattern string
+	Value   string
+
+	re *regexp.Regexp
+}
+
+// ParseCacheRule parses a rule of the form "glob=value", e.g.
+// "assets/**=public, max-age=31536000, immutable". In the glob, * matches
+// within a path segment and ** across segments. A glob without a slash
+// matches against the file name alone, so "*.html" matches at any depth.
+func ParseCacheRule(s string) (CacheRule, error) {
+	pattern, value, ok := strings.Cut(s, "=")
+	if !ok || pattern == "" {
+		return CacheRule{}, fmt.Errorf("cache rule %q: expected glob=value", s)
+	}
+
+	re, err := globRegexp(pattern)
+	if err != nil {
+		return CacheRule{}, fmt.Errorf("cache rule %q: %w", s, err)
+	}
+
+	return CacheRule{Pattern: pattern, Value: strings.TrimSpace(value), re: re}, nil
+}
+
+func (r CacheRule) Match(name string) bool {
+	if !strings.Contains(r.Pattern, "/") {
+		name = path.Base(name)
+	}
+
+	return r.re.MatchString(name)
+}
+
+// CacheControl returns the value of the first rule matching name, or "" if
+// none do.
+func CacheControl(rules []CacheRule, name string) string {
+	for _, r := range rules {
+		if r.Match(name) {
+			return r.Value
+		}
+	}
+
+	return ""
+}
+
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			// Zero or more whole directories.
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+
+	return regexp.Compile(sb.String())
+}
+
+// ContentType guesses a file's type from its extension, falling back to
+// sniffing its first bytes.
+func ContentType(name string, head []byte) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+
+	return http.DetectContentType(head)
+}
@@ -0,0 +1,433 @@
+// Package s3sync mirrors a local directory into an S3 bucket, uploading only
+// the files whose contents changed.
+package s3sync
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// API is the subset of the S3 client used here, so a local S3 stand-in or a
+// fake can be used instead.
+type API interface {
+	s3.ListObjectsV2APIClient
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+// MaxDeleteBatch is the most keys a single DeleteObjects call accepts.
+const MaxDeleteBatch = 1000
+
+// MD5MetadataKey is the user metadata (x-amz-meta-md5) each upload records
+// its contents' MD5 in, for objects whose ETag isn't one.
+const MD5MetadataKey = "md5"
+
+// headConcurrency is how many objects ListObjects inspects at once.
+const headConcurrency = 8
+
+type Object struct {
+	Key  string
+	ETag string
+	Size int64
+
+	// MD5 is the hex MD5 of the object's contents, or "" if it isn't known.
+	MD5 string
+}
+
+type LocalFile struct {
+	// Name is the slash-separated path relative to the synced directory,
+	// which is also the object's key relative to the prefix.
+	Name string
+	Path string
+	MD5  string
+	Size int64
+}
+
+// Action is a single upload or deletion. Op is '+' for a new file, '~' for a
+// changed one and '-' for an object with no local file.
+type Action struct {
+	Op   byte
+	Name string
+}
+
+// NormalizePrefix turns an origin path such as /site into the key prefix
+// site/. An empty path is the bucket root.
+func NormalizePrefix(p string) string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return ""
+	}
+
+	return p + "/"
+}
+
+// ListObjects returns the objects under prefix, keyed by their name relative
+// to it. The ETag of an object uploaded in one part without KMS or
+// customer-provided keys is its MD5; for any other object, the MD5 is read
+// from the metadata set when it was uploaded, if any.
+func ListObjects(ctx context.Context, api API, bucket string, prefix string) (map[string]Object, error) {
+	tbr := map[string]Object{}
+	var unknown []string
+
+	p := s3.NewListObjectsV2Paginator(api, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for p.HasMorePages() {
+		res, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("aws: s3: list objects (%s): %w", bucket, err)
+		}
+
+		for _, o := range res.Contents {
+			key := aws.ToString(o.Key)
+			name := strings.TrimPrefix(key, prefix)
+			if name == "" || strings.HasSuffix(name, "/") {
+				continue
+			}
+
+			obj := Object{
+				Key:  key,
+				ETag: strings.Trim(aws.ToString(o.ETag), `"`),
+				Size: aws.ToInt64(o.Size),
+			}
+
+			if isMD5(obj.ETag) {
+				obj.MD5 = obj.ETag
+			} else {
+				unknown = append(unknown, name)
+			}
+
+			tbr[name] = obj
+		}
+	}
+
+	if err := readMD5s(ctx, api, bucket, tbr, unknown); err != nil {
+		return nil, err
+	}
+
+	return tbr, nil
+}
+
+// isMD5 reports whether an ETag is a plain hex MD5, rather than e.g. the
+// "<hash>-<parts>" form of a multipart upload.
+func isMD5(etag string) bool {
+	if len(etag) != 32 {
+		return false
+	}
+
+	_, err := hex.DecodeString(etag)
+	return err == nil
+}
+
+// readMD5s fills in the MD5 of the named objects from their metadata.
+func readMD5s(ctx context.Context, api API, bucket string, objs map[string]Object, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	sums := make([]string, len(names))
+	errs := make(chan error, len(names))
+	work := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < headConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range work {
+				key := objs[names[j]].Key
+
+				res, err := api.HeadObject(ctx, &s3.HeadObjectInput{
+					Bucket: aws.String(bucket),
+					Key:    aws.String(key),
+				})
+				if err != nil {
+					errs <- fmt.Errorf("aws: s3: head object (%s): %w", key, err)
+					continue
+				}
+
+				sums[j] = res.Metadata[MD5MetadataKey]
+			}
+		}()
+	}
+
+	for j := range names {
+		work <- j
+	}
+	close(work)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	for j, name := range names {
+		o := objs[name]
+		o.MD5 = sums[j]
+		objs[name] = o
+	}
+
+	return nil
+}
+
+// ScanDir returns every file under dir with the MD5 of its contents, which is
+// what S3 reports as the ETag of an object uploaded in one part.
+func ScanDir(dir string) (map[string]LocalFile, error) {
+	tbr := map[string]LocalFile{}
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		sum, size, err := md5File(p)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(rel)
+		tbr[name] = LocalFile{Name: name, Path: p, MD5: sum, Size: size}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", dir, err)
+	}
+
+	return tbr, nil
+}
+
+func md5File(p string) (string, int64, error) {
+	fp, err := os.Open(p)
+	if err != nil {
+		return "", 0, fmt.Errorf("os: open: %w", err)
+	}
+	defer fp.Close()
+
+	h := md5.New()
+	n, err := io.Copy(h, fp)
+	if err != nil {
+		return "", 0, fmt.Errorf("read %s: %w", p, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// Plan compares local files with remote objects. Objects whose MD5 isn't
+// known, e.g. ones uploaded in several parts by another tool, are always
+// re-uploaded. With force, every file is uploaded, e.g. to apply new cache
+// rules.
+func Plan(local map[string]LocalFile, remote map[string]Object, deletes bool, force bool) []Action {
+	var tbr []Action
+
+	for name, f := range local {
+		o, ok := remote[name]
+		switch {
+		case !ok:
+			tbr = append(tbr, Action{Op: '+', Name: name})
+		case force || o.MD5 != f.MD5 || o.Size != f.Size:
+			tbr = append(tbr, Action{Op: '~', Name: name})
+		}
+	}
+
+	if deletes {
+		for name := range remote {
+			if _, ok := local[name]; !ok {
+				tbr = append(tbr, Action{Op: '-', Name: name})
+			}
+		}
+	}
+
+	sort.Slice(tbr, func(i, j int) bool {
+		return tbr[i].Name < tbr[j].Name
+	})
+
+	return tbr
+}
+
+type Syncer struct {
+	API    API
+	Bucket string
+	Prefix string
+
+	Rules               []CacheRule
+	DefaultCacheControl string
+
+	// Concurrency is how many uploads run at once; 0 means 1.
+	Concurrency int
+
+	Logf func(string, ...any)
+}
+
+// Headers returns the Content-Type and Cache-Control a file is uploaded
+// with.
+func (s *Syncer) Headers(f LocalFile) (string, string, error) {
+	fp, err := os.Open(f.Path)
+	if err != nil {
+		return "", "", fmt.Errorf("os: open: %w", err)
+	}
+	defer fp.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(fp, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", "", fmt.Errorf("read %s: %w", f.Path, err)
+	}
+
+	cc := CacheControl(s.Rules, f.Name)
+	if cc == "" {
+		cc = s.DefaultCacheControl
+	}
+
+	return ContentType(f.Name, head[:n]), cc, nil
+}
+
+// Apply uploads new and changed files, then deletes removed objects, so the
+// bucket never serves pages that refer to files not yet uploaded.
+func (s *Syncer) Apply(ctx context.Context, local map[string]LocalFile, actions []Action) error {
+	var uploads, deletes []string
+	for _, a := range actions {
+		if a.Op == '-' {
+			deletes = append(deletes, a.Name)
+		} else {
+			uploads = append(uploads, a.Name)
+		}
+	}
+
+	if err := s.upload(ctx, local, uploads); err != nil {
+		return err
+	}
+
+	return s.delete(ctx, deletes)
+}
+
+func (s *Syncer) upload(ctx context.Context, local map[string]LocalFile, names []string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	work := make(chan string)
+	errs := make(chan error, len(names))
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(s.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				if err := s.put(ctx, local[name]); err != nil {
+					errs <- err
+					cancel()
+				}
+			}
+		}()
+	}
+
+loop:
+	for _, name := range names {
+		select {
+		case work <- name:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(work)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (s *Syncer) put(ctx context.Context, f LocalFile) error {
+	contentType, cacheControl, err := s.Headers(f)
+	if err != nil {
+		return err
+	}
+
+	fp, err := os.Open(f.Path)
+	if err != nil {
+		return fmt.Errorf("os: open: %w", err)
+	}
+	defer fp.Close()
+
+	in := &s3.PutObjectInput{
+		Bucket:        aws.String(s.Bucket),
+		Key:           aws.String(s.Prefix + f.Name),
+		Body:          fp,
+		ContentLength: aws.Int64(f.Size),
+		ContentType:   aws.String(contentType),
+		Metadata:      map[string]string{MD5MetadataKey: f.MD5},
+	}
+
+	if cacheControl != "" {
+		in.CacheControl = aws.String(cacheControl)
+	}
+
+	if _, err := s.API.PutObject(ctx, in); err != nil {
+		return fmt.Errorf("aws: s3: put object (%s): %w", s.Prefix+f.Name, err)
+	}
+
+	s.logf("uploaded %s (%s, %s)", f.Name, contentType, cacheControl)
+	return nil
+}
+
+func (s *Syncer) delete(ctx context.Context, names []string) error {
+	for i := 0; i < len(names); i += MaxDeleteBatch {
+		batch := names[i:min(i+MaxDeleteBatch, len(names))]
+
+		objs := make([]s3types.ObjectIdentifier, len(batch))
+		for j, name := range batch {
+			objs[j] = s3types.ObjectIdentifier{Key: aws.String(s.Prefix + name)}
+		}
+
+		res, err := s.API.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.Bucket),
+			Delete: &s3types.Delete{Objects: objs, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("aws: s3: delete objects: %w", err)
+		}
+
+		if len(res.Errors) > 0 {
+			e := res.Errors[0]
+			return fmt.Errorf("aws: s3: delete objects: %d failed, first %s: %s", len(res.Errors), aws.ToString(e.Key), aws.ToString(e.Message))
+		}
+
+		s.logf("deleted %d objects", len(batch))
+	}
+
+	return nil
+}
+
+func (s *Syncer) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
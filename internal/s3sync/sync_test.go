@@ -0,0 +1,330 @@
+package s3sync
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type fakeObject struct {
+	body         []byte
+	etag         string
+	contentType  string
+	cacheControl string
+	metadata     map[string]string
+}
+
+// fakeS3 is an in-memory bucket. With opaqueETags, ETags aren't MD5s, as
+// with multipart uploads or KMS encryption.
+type fakeS3 struct {
+	mu          sync.Mutex
+	objects     map[string]fakeObject
+	opaqueETags bool
+	heads       int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string]fakeObject{}}
+}
+
+func (f *fakeS3) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := &s3.ListObjectsV2Output{}
+	for _, key := range slices.Sorted(maps.Keys(f.objects)) {
+		if !strings.HasPrefix(key, aws.ToString(in.Prefix)) {
+			continue
+		}
+
+		o := f.objects[key]
+		out.Contents = append(out.Contents, s3types.Object{
+			Key:  aws.String(key),
+			ETag: aws.String(`"` + o.etag + `"`),
+			Size: aws.Int64(int64(len(o.body))),
+		})
+	}
+
+	return out, nil
+}
+
+func (f *fakeS3) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.heads++
+
+	o, ok := f.objects[aws.ToString(in.Key)]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+
+	return &s3.HeadObjectOutput{Metadata: o.metadata}, nil
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.objects[aws.ToString(in.Key)] = fakeObject{
+		body:         body,
+		etag:         f.etag(body),
+		contentType:  aws.ToString(in.ContentType),
+		cacheControl: aws.ToString(in.CacheControl),
+		metadata:     in.Metadata,
+	}
+
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, o := range in.Delete.Objects {
+		delete(f.objects, aws.ToString(o.Key))
+	}
+
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+func (f *fakeS3) etag(body []byte) string {
+	sum := md5.Sum(body)
+	if f.opaqueETags {
+		return hex.EncodeToString(sum[:]) + "-1"
+	}
+
+	return hex.EncodeToString(sum[:])
+}
+
+// seed stores an object as another tool would, without MD5 metadata.
+func (f *fakeS3) seed(key string, body string) {
+	f.objects[key] = fakeObject{body: []byte(body), etag: f.etag([]byte(body))}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, body := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func plan(t *testing.T, api API, dir string, deletes, force bool) (map[string]LocalFile, []Action) {
+	t.Helper()
+
+	local, err := ScanDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := ListObjects(context.Background(), api, "bucket", "site/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return local, Plan(local, remote, deletes, force)
+}
+
+func describe(actions []Action) []string {
+	var tbr []string
+	for _, a := range actions {
+		tbr = append(tbr, fmt.Sprintf("%c %s", a.Op, a.Name))
+	}
+
+	return tbr
+}
+
+func TestSync(t *testing.T) {
+	for _, opaque := range []bool{false, true} {
+		t.Run(fmt.Sprintf("opaque etags %v", opaque), func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"index.html":        "<!doctype html><p>new</p>",
+				"about.html":        "<!doctype html><p>about</p>",
+				"assets/app.js":     "console.log(1)",
+				"assets/img/a.webp": "RIFF....WEBP",
+			})
+
+			api := newFakeS3()
+			api.opaqueETags = opaque
+			api.seed("site/index.html", "<!doctype html><p>old</p>")
+			api.seed("site/about.html", "<!doctype html><p>about</p>")
+			api.seed("site/old.html", "gone")
+			api.seed("other/keep.txt", "outside the prefix")
+
+			rules := []CacheRule{}
+			for _, r := range []string{"assets/**=public, max-age=31536000, immutable", "*.html=no-cache"} {
+				rule, err := ParseCacheRule(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rules = append(rules, rule)
+			}
+
+			local, actions := plan(t, api, dir, true, false)
+
+			want := []string{"~ about.html", "+ assets/app.js", "+ assets/img/a.webp", "~ index.html", "- old.html"}
+			if !opaque {
+				// With MD5 ETags, the unchanged file is recognized even
+				// without metadata.
+				want = slices.Delete(want, 0, 1)
+			}
+			if got := describe(actions); !slices.Equal(got, want) {
+				t.Fatalf("plan: got %v, want %v", got, want)
+			}
+
+			if !opaque && api.heads != 0 {
+				t.Errorf("got %d HeadObject calls for objects with MD5 ETags", api.heads)
+			}
+
+			syncer := &Syncer{
+				API:                 api,
+				Bucket:              "bucket",
+				Prefix:              "site/",
+				Rules:               rules,
+				DefaultCacheControl: "public, max-age=60",
+				Concurrency:         3,
+			}
+			if err := syncer.Apply(context.Background(), local, actions); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := api.objects["site/old.html"]; ok {
+				t.Error("site/old.html wasn't deleted")
+			}
+			if _, ok := api.objects["other/keep.txt"]; !ok {
+				t.Error("other/keep.txt outside the prefix was deleted")
+			}
+
+			headers := map[string][2]string{
+				"site/index.html":        {"text/html; charset=utf-8", "no-cache"},
+				"site/assets/app.js":     {"text/javascript; charset=utf-8", "public, max-age=31536000, immutable"},
+				"site/assets/img/a.webp": {"image/webp", "public, max-age=31536000, immutable"},
+			}
+			for key, want := range headers {
+				o := api.objects[key]
+				if got := [2]string{o.contentType, o.cacheControl}; got != want {
+					t.Errorf("%s: got %q, want %q", key, got, want)
+				}
+				if o.metadata[MD5MetadataKey] != local[strings.TrimPrefix(key, "site/")].MD5 {
+					t.Errorf("%s: got md5 metadata %q", key, o.metadata[MD5MetadataKey])
+				}
+			}
+
+			// Everything is in sync now, including files whose ETag isn't an
+			// MD5.
+			if _, actions := plan(t, api, dir, true, false); len(actions) != 0 {
+				t.Errorf("re-plan: got %v, want nothing", describe(actions))
+			}
+
+			// Without deletes, objects with no local file are left alone.
+			api.seed("site/stray.html", "stray")
+			if _, actions := plan(t, api, dir, false, false); len(actions) != 0 {
+				t.Errorf("plan without deletes: got %v, want nothing", describe(actions))
+			}
+
+			_, actions = plan(t, api, dir, false, true)
+			want = []string{"~ about.html", "~ assets/app.js", "~ assets/img/a.webp", "~ index.html"}
+			if got := describe(actions); !slices.Equal(got, want) {
+				t.Errorf("forced plan: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	var rules []CacheRule
+	for _, r := range []string{
+		"assets/**=immutable",
+		"*.html=no-cache",
+		"docs/*/index.json=short",
+		"**/*.map=private",
+	} {
+		rule, err := ParseCacheRule(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+
+	tests := map[string]string{
+		"assets/app.js":        "immutable",
+		"assets/css/site.css":  "immutable",
+		"assets/index.html":    "immutable",
+		"index.html":           "no-cache",
+		"blog/post/index.html": "no-cache",
+		"docs/v1/index.json":   "short",
+		"docs/v1/x/index.json": "",
+		"js/app.js.map":        "private",
+		"app.js.map":           "private",
+		"assetsfoo/app.js":     "",
+		"robots.txt":           "",
+	}
+
+	for name, want := range tests {
+		if got := CacheControl(rules, name); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParseCacheRule(t *testing.T) {
+	r, err := ParseCacheRule("*.css= public, max-age=60 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Pattern != "*.css" || r.Value != "public, max-age=60" {
+		t.Errorf("got pattern %q, value %q", r.Pattern, r.Value)
+	}
+
+	for _, bad := range []string{"", "no-value", "=public"} {
+		if _, err := ParseCacheRule(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"style.css", "", "text/css; charset=utf-8"},
+		{"LICENSE", "plain text", "text/plain; charset=utf-8"},
+		{"blob", "\x89PNG\r\n\x1a\n", "image/png"},
+	}
+
+	for _, tt := range tests {
+		if got := ContentType(tt.name, []byte(tt.head)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
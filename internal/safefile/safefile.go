@@ -0,0 +1,151 @@
+package safefile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const DefaultMode os.FileMode = 0o600
+
+// ErrExists is returned by Write when path already exists and
+// Options.Overwrite isn't set.
+var ErrExists = errors.New("file already exists")
+
+// link is os.Link, replaced in tests to exercise commitNew's fallback.
+var link = os.Link
+
+type Options struct {
+	Mode      os.FileMode
+	Overwrite bool
+}
+
+// Write copies r into a temp file next to path, fsyncs it and then moves it
+// into place, so readers never see a partially written file. Unless
+// opts.Overwrite is set, an existing file at path is left alone and an error
+// wrapping ErrExists is returned.
+func Write(path string, r io.Reader, opts Options) (int64, error) {
+	mode := opts.Mode
+	if mode == 0 {
+		mode = DefaultMode
+	}
+
+	if !opts.Overwrite {
+		if _, err := os.Lstat(path); err == nil {
+			return 0, fmt.Errorf("%s: %w", path, ErrExists)
+		}
+	}
+
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	fp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return 0, fmt.Errorf("os: create temp: %w", err)
+	}
+
+	tmp := fp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			fp.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	if err := fp.Chmod(mode); err != nil {
+		return 0, fmt.Errorf("os: chmod: %w", err)
+	}
+
+	n, err := io.Copy(fp, r)
+	if err != nil {
+		return n, fmt.Errorf("write: %w", err)
+	}
+
+	if err := fp.Sync(); err != nil {
+		return n, fmt.Errorf("os: sync: %w", err)
+	}
+
+	if err := fp.Close(); err != nil {
+		return n, fmt.Errorf("os: close: %w", err)
+	}
+
+	if opts.Overwrite {
+		if err := os.Rename(tmp, path); err != nil {
+			return n, fmt.Errorf("os: rename: %w", err)
+		}
+	} else if err := commitNew(tmp, path); err != nil {
+		return n, err
+	}
+
+	committed = true
+
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return n, nil
+}
+
+// commitNew moves tmp to path unless path exists. Link fails if path was
+// created since Write's check, which keeps the no-overwrite guarantee without
+// a race. Where hard links aren't supported (some FUSE, SMB and container
+// mounts), path is claimed with an exclusive create and then replaced.
+func commitNew(tmp, path string) error {
+	err := link(tmp, path)
+	if err == nil {
+		os.Remove(tmp)
+		return nil
+	}
+
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s: %w", path, ErrExists)
+	}
+
+	fp, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s: %w", path, ErrExists)
+		}
+		return fmt.Errorf("os: create: %w", err)
+	}
+	fp.Close()
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("os: rename: %w", err)
+	}
+
+	return nil
+}
+
+// ForceHint adds a hint to use -force to an ErrExists error from Write, for
+// commands whose -force flag sets Options.Overwrite. Other errors are returned
+// unchanged.
+func ForceHint(err error) error {
+	if errors.Is(err, ErrExists) {
+		return fmt.Errorf("%w (use -force to overwrite)", err)
+	}
+
+	return err
+}
+
+func ParseMode(s string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid file mode %q: %w", s, err)
+	}
+
+	if m == 0 || m > 0o777 {
+		return 0, fmt.Errorf("invalid file mode %q", s)
+	}
+
+	return os.FileMode(m), nil
+}
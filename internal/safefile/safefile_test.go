@@ -0,0 +1,199 @@
+package safefile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	by, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(by)
+}
+
+// noTemps checks that Write didn't leave temp files behind in dir.
+func noTemps(t *testing.T, dir string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("temp file %s left behind", e.Name())
+		}
+	}
+}
+
+// withLink replaces link for the duration of the test.
+func withLink(t *testing.T, fn func(oldname, newname string) error) {
+	t.Helper()
+
+	orig := link
+	link = fn
+	t.Cleanup(func() { link = orig })
+}
+
+func TestWriteMode(t *testing.T) {
+	tests := []struct {
+		mode os.FileMode
+		want os.FileMode
+	}{
+		{mode: 0, want: DefaultMode},
+		{mode: 0o640, want: 0o640},
+		{mode: 0o755, want: 0o755},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "out")
+
+		if _, err := Write(path, strings.NewReader("secret"), Options{Mode: tt.mode}); err != nil {
+			t.Fatal(err)
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Chmod isn't subject to the umask, so the mode is exact.
+		if got := fi.Mode().Perm(); got != tt.want {
+			t.Errorf("mode %o: got %o, want %o", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestWriteOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out")
+
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Write(path, strings.NewReader("new"), Options{}); !errors.Is(err, ErrExists) {
+		t.Fatalf("got error %v, want %v", err, ErrExists)
+	}
+
+	if got := readFile(t, path); got != "old" {
+		t.Errorf("got %q, want the existing file left alone", got)
+	}
+
+	if _, err := Write(path, strings.NewReader("new"), Options{Overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, path); got != "new" {
+		t.Errorf("got %q after overwriting, want %q", got, "new")
+	}
+
+	noTemps(t, dir)
+}
+
+// TestWriteRace creates path after Write's existence check but before the
+// file is moved into place.
+func TestWriteRace(t *testing.T) {
+	tests := []struct {
+		name string
+		link func(oldname, newname string) error
+	}{
+		{
+			name: "link",
+			link: os.Link,
+		},
+		{
+			name: "no hard links",
+			link: func(oldname, newname string) error {
+				return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EPERM}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "out")
+
+			withLink(t, func(oldname, newname string) error {
+				if err := os.WriteFile(newname, []byte("theirs"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+
+				return tt.link(oldname, newname)
+			})
+
+			if _, err := Write(path, strings.NewReader("ours"), Options{}); !errors.Is(err, ErrExists) {
+				t.Fatalf("got error %v, want %v", err, ErrExists)
+			}
+
+			if got := readFile(t, path); got != "theirs" {
+				t.Errorf("got %q, want the other writer's file left alone", got)
+			}
+
+			noTemps(t, dir)
+		})
+	}
+}
+
+func TestWriteWithoutHardLinks(t *testing.T) {
+	withLink(t, func(oldname, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.ENOTSUP}
+	})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out")
+
+	if _, err := Write(path, strings.NewReader("secret"), Options{Mode: 0o640}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, path); got != "secret" {
+		t.Errorf("got %q, want %q", got, "secret")
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fi.Mode().Perm(); got != 0o640 {
+		t.Errorf("mode: got %o, want %o", got, 0o640)
+	}
+
+	noTemps(t, dir)
+}
+
+func TestForceHint(t *testing.T) {
+	err := ForceHint(ErrExists)
+	if !errors.Is(err, ErrExists) || !strings.Contains(err.Error(), "-force") {
+		t.Errorf("got %v, want a hint wrapping %v", err, ErrExists)
+	}
+
+	other := errors.New("disk full")
+	if got := ForceHint(other); got != other {
+		t.Errorf("got %v, want %v unchanged", got, other)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if m, err := ParseMode("0640"); err != nil || m != 0o640 {
+		t.Errorf("0640: got %o, %v", m, err)
+	}
+
+	for _, bad := range []string{"", "0", "8", "1000", "rw-"} {
+		if _, err := ParseMode(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
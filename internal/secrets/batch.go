@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// BatchGetSecretValue accepts at most this many IDs per call.
+const batchSize = 20
+
+type Value struct {
+	Name      string
+	ARN       string
+	VersionID string
+	String    string
+	Binary    []byte
+}
+
+type BatchError struct {
+	SecretID string
+	Code     string
+	Message  string
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.SecretID, e.Code, e.Message)
+}
+
+// BatchGet fetches the current value of every secret in names, plus every
+// secret matching filters if any are given. Per-secret failures are returned
+// as BatchErrors alongside the values that could be fetched; the error return
+// is reserved for failures of the batch call itself.
+func BatchGet(ctx context.Context, sm secretsmanager.BatchGetSecretValueAPIClient, names []string, filters []smtypes.Filter) ([]Value, []BatchError, error) {
+	var inputs []*secretsmanager.BatchGetSecretValueInput
+
+	for i := 0; i < len(names); i += batchSize {
+		sl := names[i:]
+		if len(sl) > batchSize {
+			sl = sl[:batchSize]
+		}
+
+		inputs = append(inputs, &secretsmanager.BatchGetSecretValueInput{
+			SecretIdList: sl,
+		})
+	}
+
+	if len(filters) > 0 {
+		inputs = append(inputs, &secretsmanager.BatchGetSecretValueInput{
+			Filters:    filters,
+			MaxResults: aws.Int32(batchSize),
+		})
+	}
+
+	var values []Value
+	var errs []BatchError
+	seen := map[string]bool{}
+
+	for _, in := range inputs {
+		p := secretsmanager.NewBatchGetSecretValuePaginator(sm, in)
+		for p.HasMorePages() {
+			res, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, nil, fmt.Errorf("secrets manager: batch get secret value: %w", err)
+			}
+
+			for _, v := range res.SecretValues {
+				arn := aws.ToString(v.ARN)
+				if seen[arn] {
+					continue
+				}
+				seen[arn] = true
+
+				values = append(values, Value{
+					Name:      aws.ToString(v.Name),
+					ARN:       arn,
+					VersionID: aws.ToString(v.VersionId),
+					String:    aws.ToString(v.SecretString),
+					Binary:    v.SecretBinary,
+				})
+			}
+
+			for _, e := range res.Errors {
+				errs = append(errs, BatchError{
+					SecretID: aws.ToString(e.SecretId),
+					Code:     aws.ToString(e.ErrorCode),
+					Message:  aws.ToString(e.Message),
+				})
+			}
+		}
+	}
+
+	return values, errs, nil
+}
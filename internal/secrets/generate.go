@@ -0,0 +1,78 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// Generator produces new secret values for client-side rotation.
+type Generator interface {
+	Generate() (string, error)
+}
+
+const (
+	alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	punctuation  = "!#$%&()*+,-.:;<=>?@[]^_{|}~"
+)
+
+type PasswordGenerator struct {
+	Length             int
+	ExcludePunctuation bool
+}
+
+func (g PasswordGenerator) Generate() (string, error) {
+	alphabet := alphanumeric
+	if !g.ExcludePunctuation {
+		alphabet += punctuation
+	}
+
+	length := g.Length
+	if length <= 0 {
+		length = 32
+	}
+
+	out := make([]byte, length)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("rand: int: %w", err)
+		}
+		out[i] = alphabet[n.Int64()]
+	}
+
+	return string(out), nil
+}
+
+type APIKeyGenerator struct {
+	Bytes int
+}
+
+func (g APIKeyGenerator) Generate() (string, error) {
+	n := g.Bytes
+	if n <= 0 {
+		n = 32
+	}
+
+	by := make([]byte, n)
+	if _, err := rand.Read(by); err != nil {
+		return "", fmt.Errorf("rand: read: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(by), nil
+}
+
+func NewGenerator(kind string, length int) (Generator, error) {
+	switch kind {
+	case "password":
+		return PasswordGenerator{Length: length}, nil
+	case "alphanumeric":
+		return PasswordGenerator{Length: length, ExcludePunctuation: true}, nil
+	case "api-key":
+		return APIKeyGenerator{Bytes: length}, nil
+	default:
+		return nil, fmt.Errorf("unknown generator: %s (expected password, alphanumeric or api-key)", kind)
+	}
+}
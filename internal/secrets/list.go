@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// List returns every secret matching filters.
+func List(ctx context.Context, sm secretsmanager.ListSecretsAPIClient, filters []smtypes.Filter) ([]smtypes.SecretListEntry, error) {
+	var tbr []smtypes.SecretListEntry
+
+	p := secretsmanager.NewListSecretsPaginator(sm, &secretsmanager.ListSecretsInput{
+		Filters: filters,
+	})
+	for p.HasMorePages() {
+		res, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("secrets manager: list secrets: %w", err)
+		}
+
+		tbr = append(tbr, res.SecretList...)
+	}
+
+	return tbr, nil
+}
+
+// HasTag reports whether tags includes key, with value if hasValue is set.
+// The tag-key filter matches on prefix and the tag-value filter matches any
+// tag, so filtered results need checking with this.
+func HasTag(tags []smtypes.Tag, key, value string, hasValue bool) bool {
+	for _, t := range tags {
+		if aws.ToString(t.Key) == key && (!hasValue || aws.ToString(t.Value) == value) {
+			return true
+		}
+	}
+
+	return false
+}
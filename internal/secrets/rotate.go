@@ -0,0 +1,182 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+var (
+	ErrRotationStalled     = errors.New("rotation stalled: pending version was not promoted to " + StageCurrent)
+	ErrRotationFailed      = errors.New("rotation failed: pending version was removed")
+	ErrPreviousNotRetained = errors.New("rotation incomplete: old version is not labelled " + StagePrevious)
+)
+
+type Rotation struct {
+	Name       string
+	OldVersion string
+	NewVersion string
+	Stages     map[string][]string
+	Took       time.Duration
+}
+
+type Rotator struct {
+	API      API
+	Interval time.Duration
+	Timeout  time.Duration
+	Logf     func(format string, args ...any)
+}
+
+// Rotate asks Secrets Manager to rotate name with its configured rotation
+// function, then waits for the new version to be promoted.
+func (r *Rotator) Rotate(ctx context.Context, name string) (*Rotation, error) {
+	start := time.Now()
+
+	before, err := Stages(ctx, r.API, name)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := ClientRequestToken()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.API.RotateSecret(ctx, &secretsmanager.RotateSecretInput{
+		SecretId:           aws.String(name),
+		ClientRequestToken: aws.String(token),
+		RotateImmediately:  aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager: rotate secret (%s): %w", name, err)
+	}
+
+	rot := &Rotation{
+		Name:       name,
+		OldVersion: VersionWithStage(before, StageCurrent),
+		NewVersion: aws.ToString(res.VersionId),
+	}
+
+	r.logf("%s: rotation started, pending version %s", name, rot.NewVersion)
+
+	return r.wait(ctx, rot, start)
+}
+
+// RotateWithValue performs a client-side rotation: value is stored as a new
+// AWSPENDING version, which is then promoted to AWSCURRENT.
+func (r *Rotator) RotateWithValue(ctx context.Context, name string, value string) (*Rotation, error) {
+	start := time.Now()
+
+	before, err := Stages(ctx, r.API, name)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := ClientRequestToken()
+	if err != nil {
+		return nil, err
+	}
+
+	rot := &Rotation{
+		Name:       name,
+		OldVersion: VersionWithStage(before, StageCurrent),
+	}
+
+	res, err := r.API.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:           aws.String(name),
+		ClientRequestToken: aws.String(token),
+		SecretString:       aws.String(value),
+		VersionStages:      []string{StagePending},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager: put secret value (%s): %w", name, err)
+	}
+
+	rot.NewVersion = aws.ToString(res.VersionId)
+
+	r.logf("%s: pending version %s created", name, rot.NewVersion)
+
+	promote := &secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:        aws.String(name),
+		VersionStage:    aws.String(StageCurrent),
+		MoveToVersionId: aws.String(rot.NewVersion),
+	}
+	if rot.OldVersion != "" {
+		promote.RemoveFromVersionId = aws.String(rot.OldVersion)
+	}
+
+	if _, err := r.API.UpdateSecretVersionStage(ctx, promote); err != nil {
+		return nil, fmt.Errorf("secrets manager: update secret version stage (%s): %w", name, err)
+	}
+
+	if _, err := r.API.UpdateSecretVersionStage(ctx, &secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:            aws.String(name),
+		VersionStage:        aws.String(StagePending),
+		RemoveFromVersionId: aws.String(rot.NewVersion),
+	}); err != nil {
+		return nil, fmt.Errorf("secrets manager: update secret version stage (%s): %w", name, err)
+	}
+
+	return r.wait(ctx, rot, start)
+}
+
+func (r *Rotator) wait(ctx context.Context, rot *Rotation, start time.Time) (*Rotation, error) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	for {
+		stages, err := Stages(ctx, r.API, rot.Name)
+		if err != nil && ctx.Err() == nil {
+			return rot, err
+		}
+
+		if err == nil {
+			rot.Stages = stages
+			rot.Took = time.Since(start)
+
+			labels, ok := stages[rot.NewVersion]
+			if !ok {
+				return rot, ErrRotationFailed
+			}
+
+			if slices.Contains(labels, StageCurrent) {
+				if rot.OldVersion != "" && !slices.Contains(stages[rot.OldVersion], StagePrevious) {
+					return rot, ErrPreviousNotRetained
+				}
+
+				return rot, nil
+			}
+
+			r.logf("%s: waiting for %s to be promoted (stages: %v)", rot.Name, rot.NewVersion, labels)
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return rot, ErrRotationStalled
+			}
+			return rot, ctx.Err()
+
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (r *Rotator) logf(format string, args ...any) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+	}
+}
@@ -0,0 +1,197 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// fakeAPI keeps a single secret's version stages in memory. rotation decides
+// what the secret's rotation function does with a pending version: "promote"
+// makes it current, "stall" leaves it pending and "fail" removes it. A stalled
+// secret also accepts stage changes without applying them, so client-side
+// rotations stay pending too. With dropPrevious, the old version loses
+// AWSCURRENT without being labelled AWSPREVIOUS.
+type fakeAPI struct {
+	stages       map[string][]string
+	versions     int
+	rotation     string
+	stageErr     error
+	dropPrevious bool
+	describes    int
+}
+
+func newFakeAPI(rotation string) *fakeAPI {
+	return &fakeAPI{
+		stages:   map[string][]string{"v0": {StageCurrent}},
+		rotation: rotation,
+	}
+}
+
+func (f *fakeAPI) DescribeSecret(ctx context.Context, in *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error) {
+	f.describes++
+
+	// Let the rotation function run once the rotator starts polling.
+	if f.describes > 1 {
+		for id, labels := range f.stages {
+			if !slices.Contains(labels, StagePending) {
+				continue
+			}
+
+			switch f.rotation {
+			case "promote":
+				f.move(StageCurrent, id)
+				f.remove(StagePending, id)
+			case "fail":
+				delete(f.stages, id)
+			}
+		}
+	}
+
+	out := map[string][]string{}
+	for id, labels := range f.stages {
+		out[id] = slices.Clone(labels)
+	}
+
+	return &secretsmanager.DescribeSecretOutput{
+		Name:               in.SecretId,
+		VersionIdsToStages: out,
+	}, nil
+}
+
+func (f *fakeAPI) GetSecretValue(ctx context.Context, in *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeAPI) PutSecretValue(ctx context.Context, in *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error) {
+	id := f.newVersion()
+	f.stages[id] = slices.Clone(in.VersionStages)
+
+	return &secretsmanager.PutSecretValueOutput{VersionId: aws.String(id)}, nil
+}
+
+func (f *fakeAPI) RotateSecret(ctx context.Context, in *secretsmanager.RotateSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.RotateSecretOutput, error) {
+	id := f.newVersion()
+	f.stages[id] = []string{StagePending}
+
+	return &secretsmanager.RotateSecretOutput{VersionId: aws.String(id)}, nil
+}
+
+func (f *fakeAPI) UpdateSecretVersionStage(ctx context.Context, in *secretsmanager.UpdateSecretVersionStageInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.UpdateSecretVersionStageOutput, error) {
+	if f.stageErr != nil {
+		return nil, f.stageErr
+	}
+
+	if f.rotation == "stall" {
+		return &secretsmanager.UpdateSecretVersionStageOutput{}, nil
+	}
+
+	stage := aws.ToString(in.VersionStage)
+	if to := aws.ToString(in.MoveToVersionId); to != "" {
+		f.move(stage, to)
+	} else {
+		f.remove(stage, aws.ToString(in.RemoveFromVersionId))
+	}
+
+	return &secretsmanager.UpdateSecretVersionStageOutput{}, nil
+}
+
+func (f *fakeAPI) newVersion() string {
+	f.versions++
+	return fmt.Sprintf("v%d", f.versions)
+}
+
+// move attaches stage to version id, detaching it from any other version. As
+// in Secrets Manager, moving AWSCURRENT labels the old version AWSPREVIOUS
+// (unless dropPrevious is set).
+func (f *fakeAPI) move(stage, id string) {
+	for other, labels := range f.stages {
+		if other == id || !slices.Contains(labels, stage) {
+			continue
+		}
+
+		f.remove(stage, other)
+		if stage == StageCurrent && !f.dropPrevious {
+			f.move(StagePrevious, other)
+		}
+	}
+
+	if !slices.Contains(f.stages[id], stage) {
+		f.stages[id] = append(f.stages[id], stage)
+	}
+}
+
+func (f *fakeAPI) remove(stage, id string) {
+	f.stages[id] = slices.DeleteFunc(f.stages[id], func(s string) bool { return s == stage })
+}
+
+func TestRotate(t *testing.T) {
+	errStage := errors.New("access denied")
+
+	tests := []struct {
+		name         string
+		withValue    bool
+		rotation     string
+		stageErr     error
+		dropPrevious bool
+		wantErr      error
+	}{
+		{name: "managed success", rotation: "promote"},
+		{name: "managed stall", rotation: "stall", wantErr: ErrRotationStalled},
+		{name: "managed failure", rotation: "fail", wantErr: ErrRotationFailed},
+		{name: "client-side success", withValue: true},
+		{name: "client-side stall", withValue: true, rotation: "stall", wantErr: ErrRotationStalled},
+		{name: "client-side error", withValue: true, stageErr: errStage, wantErr: errStage},
+		{name: "managed previous dropped", rotation: "promote", dropPrevious: true, wantErr: ErrPreviousNotRetained},
+		{name: "client-side previous dropped", withValue: true, dropPrevious: true, wantErr: ErrPreviousNotRetained},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(tt.rotation)
+			api.stageErr = tt.stageErr
+			api.dropPrevious = tt.dropPrevious
+
+			r := &Rotator{
+				API:      api,
+				Interval: time.Millisecond,
+				Timeout:  50 * time.Millisecond,
+				Logf:     t.Logf,
+			}
+
+			var rot *Rotation
+			var err error
+			if tt.withValue {
+				rot, err = r.RotateWithValue(context.Background(), "secret", "new value")
+			} else {
+				rot, err = r.Rotate(context.Background(), "secret")
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if rot.OldVersion != "v0" {
+				t.Errorf("old version: got %q, want v0", rot.OldVersion)
+			}
+
+			if !slices.Contains(rot.Stages[rot.NewVersion], StageCurrent) {
+				t.Errorf("new version %s: got stages %v, want %s", rot.NewVersion, rot.Stages[rot.NewVersion], StageCurrent)
+			}
+
+			if !slices.Contains(rot.Stages[rot.OldVersion], StagePrevious) {
+				t.Errorf("old version %s: got stages %v, want %s", rot.OldVersion, rot.Stages[rot.OldVersion], StagePrevious)
+			}
+		})
+	}
+}
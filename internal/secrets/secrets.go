@@ -0,0 +1,80 @@
+package secrets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+const (
+	StageCurrent  = "AWSCURRENT"
+	StagePrevious = "AWSPREVIOUS"
+	StagePending  = "AWSPENDING"
+)
+
+// API is the subset of the Secrets Manager client used by this package, so
+// callers can substitute a fake.
+type API interface {
+	DescribeSecret(ctx context.Context, in *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error)
+	GetSecretValue(ctx context.Context, in *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+	PutSecretValue(ctx context.Context, in *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error)
+	RotateSecret(ctx context.Context, in *secretsmanager.RotateSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.RotateSecretOutput, error)
+	UpdateSecretVersionStage(ctx context.Context, in *secretsmanager.UpdateSecretVersionStageInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.UpdateSecretVersionStageOutput, error)
+}
+
+var _ API = (*secretsmanager.Client)(nil)
+
+// Stages returns the version ID to staging labels map for a secret.
+func Stages(ctx context.Context, sm API, name string) (map[string][]string, error) {
+	res, err := sm.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager: describe secret (%s): %w", name, err)
+	}
+
+	return res.VersionIdsToStages, nil
+}
+
+// VersionWithStage returns the version ID carrying stage, or "" if no
+// version has it.
+func VersionWithStage(stages map[string][]string, stage string) string {
+	for id, labels := range stages {
+		if slices.Contains(labels, stage) {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// ClientRequestToken returns a random token suitable for use as a version ID.
+func ClientRequestToken() (string, error) {
+	by := make([]byte, 16)
+	if _, err := rand.Read(by); err != nil {
+		return "", fmt.Errorf("rand: read: %w", err)
+	}
+
+	return hex.EncodeToString(by), nil
+}
+
+// DecodeObject decodes a JSON object secret. Values are kept as raw JSON so
+// they round-trip exactly, e.g. integers too large for a float64.
+func DecodeObject(s string) (map[string]json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &obj); err != nil {
+		return nil, fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+
+	if obj == nil {
+		return nil, fmt.Errorf("secret is not a JSON object")
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,20 @@
+package secrets
+
+import "testing"
+
+func TestDecodeObject(t *testing.T) {
+	obj, err := DecodeObject(`{"id": 12345678901234567890, "name": "app"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(obj["id"]); got != "12345678901234567890" {
+		t.Errorf("id: got %s, want it unchanged", got)
+	}
+
+	for _, bad := range []string{"", "hunter2", "null", `["a"]`, `"a"`} {
+		if _, err := DecodeObject(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
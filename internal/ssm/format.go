@@ -0,0 +1,83 @@
+package ssm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatDotenv = "dotenv"
+	FormatExport = "export"
+	FormatJSON   = "json"
+)
+
+func WriteParams(w io.Writer, params []Param, format string) error {
+	switch format {
+	case FormatDotenv, FormatExport, "":
+		// These are sourced by shells, so a name like "A;rm -rf ~" mustn't
+		// make it into the output.
+		for _, v := range params {
+			if !ValidEnvName(v.Name) {
+				return fmt.Errorf("%q isn't a valid environment variable name", v.Name)
+			}
+		}
+	}
+
+	switch format {
+	case FormatDotenv, "":
+		for _, v := range params {
+			if _, err := fmt.Fprintf(w, "%s=%q\n", v.Name, v.Value); err != nil {
+				return fmt.Errorf("write: %w", err)
+			}
+		}
+
+	case FormatExport:
+		for _, v := range params {
+			if _, err := fmt.Fprintf(w, "export %s=%s\n", v.Name, shellQuote(v.Value)); err != nil {
+				return fmt.Errorf("write: %w", err)
+			}
+		}
+
+	case FormatJSON:
+		obj := make(map[string]string, len(params))
+		for _, v := range params {
+			obj[v.Name] = v.Value
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(obj); err != nil {
+			return fmt.Errorf("json: encode: %w", err)
+		}
+
+	default:
+		return fmt.Errorf("unknown format: %s (expected %s, %s or %s)", format, FormatDotenv, FormatExport, FormatJSON)
+	}
+
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ValidEnvName reports whether name matches [A-Za-z_][A-Za-z0-9_]*.
+func ValidEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, r := range name {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case '0' <= r && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,140 @@
+package ssm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// SecretValueGetter is the part of the Secrets Manager client that
+// GetSecretVersion needs.
+type SecretValueGetter interface {
+	GetSecretValue(ctx context.Context, in *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// GetSecretVersion fetches a secret's value, selecting a version by ID or
+// staging label if either is set (AWSCURRENT otherwise).
+func GetSecretVersion(ctx context.Context, sm SecretValueGetter, name, versionID, versionStage string) (*secretsmanager.GetSecretValueOutput, error) {
+	in := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	}
+
+	if versionID != "" {
+		in.VersionId = aws.String(versionID)
+	}
+
+	if versionStage != "" {
+		in.VersionStage = aws.String(versionStage)
+	}
+
+	res, err := sm.GetSecretValue(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager: get secret value (%s): %w", name, err)
+	}
+
+	return res, nil
+}
+
+func GetParametersFromSecret(ctx context.Context, sm *secretsmanager.Client, name string) ([]Param, error) {
+	return GetParametersFromSecretVersion(ctx, sm, name, "", "")
+}
+
+func GetParametersFromSecretVersion(ctx context.Context, sm *secretsmanager.Client, name, versionID, versionStage string) ([]Param, error) {
+	res, err := GetSecretVersion(ctx, sm, name, versionID, versionStage)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParamsFromJSON([]byte(aws.ToString(res.SecretString)))
+}
+
+func LoadParametersIntoSecret(ctx context.Context, sm *secretsmanager.Client, name string, params []Param) error {
+	res, err := GetSecretVersion(ctx, sm, name, "", "")
+	exists := true
+	if err != nil {
+		if rnf := new(smtypes.ResourceNotFoundException); !errors.As(err, &rnf) {
+			return err
+		}
+		exists = false
+	}
+
+	values := map[string]string{}
+	if exists && aws.ToString(res.SecretString) != "" {
+		existing, err := ParamsFromJSON([]byte(aws.ToString(res.SecretString)))
+		if err != nil {
+			return fmt.Errorf("existing secret (%s): %w", name, err)
+		}
+
+		for _, p := range existing {
+			values[p.Name] = p.Value
+		}
+	}
+
+	for _, p := range params {
+		if p.Value == "" {
+			continue
+		}
+
+		values[p.Name] = p.Value
+	}
+
+	by, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("json: marshal: %w", err)
+	}
+
+	if !exists {
+		if _, err := sm.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+			Name:         aws.String(name),
+			SecretString: aws.String(string(by)),
+		}); err != nil {
+			return fmt.Errorf("secrets manager: create secret (%s): %w", name, err)
+		}
+
+		return nil
+	}
+
+	if _, err := sm.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(string(by)),
+	}); err != nil {
+		return fmt.Errorf("secrets manager: put secret value (%s): %w", name, err)
+	}
+
+	return nil
+}
+
+// ParamsFromJSON converts a flat JSON object into a list of secure Params,
+// sorted by name. Non-string values are kept in their JSON form.
+func ParamsFromJSON(in []byte) ([]Param, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(in, &obj); err != nil {
+		return nil, fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+
+	tbr := make([]Param, 0, len(obj))
+	for k, raw := range obj {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			s = string(raw)
+		}
+
+		tbr = append(tbr, Param{
+			Name:   k,
+			Value:  s,
+			Secure: true,
+		})
+	}
+
+	sort.Slice(tbr, func(i, j int) bool {
+		return tbr[i].Name < tbr[j].Name
+	})
+
+	return tbr, nil
+}
@@ -0,0 +1,156 @@
+package ssm
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+const SecretsManagerScheme = "secretsmanager://"
+
+// Client reads and writes Params from either a Parameter Store path
+// ("/app/prod") or a Secrets Manager JSON secret ("secretsmanager://app/prod").
+// Secret sources may select a version for reading with a "?stage=AWSPREVIOUS"
+// or "?version=<id>" suffix.
+type Client struct {
+	SSM            *ssm.Client
+	SecretsManager *secretsmanager.Client
+}
+
+func NewFromConfig(cfg aws.Config) *Client {
+	return &Client{
+		SSM:            ssm.NewFromConfig(cfg),
+		SecretsManager: secretsmanager.NewFromConfig(cfg),
+	}
+}
+
+func ParseSource(source string) (secretName string, isSecret bool, err error) {
+	if name, ok := strings.CutPrefix(source, SecretsManagerScheme); ok {
+		if name == "" {
+			return "", false, fmt.Errorf("secret name must be present after %s", SecretsManagerScheme)
+		}
+		return name, true, nil
+	}
+
+	if source == "" || !strings.HasPrefix(source, "/") {
+		return "", false, fmt.Errorf("path must be present and start with / (or %s)", SecretsManagerScheme)
+	}
+
+	return "", false, nil
+}
+
+// Location returns the display name of a single Param within source.
+func Location(source string, name string) string {
+	if strings.HasPrefix(source, SecretsManagerScheme) {
+		return source + "#" + name
+	}
+
+	return source + "/" + name
+}
+
+func (c *Client) GetParameters(ctx context.Context, source string) ([]Param, error) {
+	name, isSecret, err := ParseSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if isSecret {
+		name, versionID, versionStage, err := splitSecretVersion(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return GetParametersFromSecretVersion(ctx, c.SecretsManager, name, versionID, versionStage)
+	}
+
+	return GetParametersFromPath(ctx, c.SSM, source)
+}
+
+func (c *Client) LoadParameters(ctx context.Context, source string, params []Param) error {
+	name, isSecret, err := ParseSource(source)
+	if err != nil {
+		return err
+	}
+
+	if isSecret {
+		if strings.Contains(name, "?") {
+			return fmt.Errorf("%s: a version can't be selected when writing", source)
+		}
+
+		return LoadParametersIntoSecret(ctx, c.SecretsManager, name, params)
+	}
+
+	return LoadParametersIntoPath(ctx, c.SSM, source, params)
+}
+
+func splitSecretVersion(name string) (string, string, string, error) {
+	name, query, ok := strings.Cut(name, "?")
+	if !ok {
+		return name, "", "", nil
+	}
+
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return "", "", "", fmt.Errorf("parse version selector %q: %w", query, err)
+	}
+
+	for k := range q {
+		if k != "stage" && k != "version" {
+			return "", "", "", fmt.Errorf("unknown version selector %q (expected stage or version)", k)
+		}
+	}
+
+	return name, q.Get("version"), q.Get("stage"), nil
+}
+
+// GetSecretValue returns the raw value of a secret source. A "#key" suffix
+// (as produced by Location) selects a single key from a JSON secret instead.
+func (c *Client) GetSecretValue(ctx context.Context, source string) (string, error) {
+	name, isSecret, err := ParseSource(source)
+	if err != nil {
+		return "", err
+	}
+
+	if !isSecret {
+		return "", fmt.Errorf("%s: not a %s source", source, SecretsManagerScheme)
+	}
+
+	name, key, _ := strings.Cut(name, "#")
+
+	name, versionID, versionStage, err := splitSecretVersion(name)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := GetSecretVersion(ctx, c.SecretsManager, name, versionID, versionStage)
+	if err != nil {
+		return "", err
+	}
+
+	value := aws.ToString(res.SecretString)
+	if res.SecretString == nil && res.SecretBinary != nil {
+		value = string(res.SecretBinary)
+	}
+
+	if key == "" {
+		return value, nil
+	}
+
+	params, err := ParamsFromJSON([]byte(value))
+	if err != nil {
+		return "", fmt.Errorf("secret (%s): %w", name, err)
+	}
+
+	for _, p := range params {
+		if p.Name == key {
+			return p.Value, nil
+		}
+	}
+
+	return "", fmt.Errorf("secret (%s) has no key %q", name, key)
+}